@@ -0,0 +1,266 @@
+package assetmin
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"sort"
+)
+
+// FileContribution is the share of one source file in a bundle
+type FileContribution struct {
+	Path         string `json:"path"`         // source file, or the name of a wrapper eg: sprite-open.svg
+	RawSize      int    `json:"rawSize"`      // bytes before minification
+	MinifiedSize int    `json:"minifiedSize"` // approximate: the file minified alone, RawSize when it can't be
+}
+
+// BundleAnalysis describes the composition of one asset
+type BundleAnalysis struct {
+	Asset        string             `json:"asset"` // output name eg: script.js
+	MediaType    string             `json:"mediaType"`
+	RawSize      int                `json:"rawSize"`      // bytes of the bundle before minification
+	MinifiedSize int                `json:"minifiedSize"` // bytes of the served output
+	Files        []FileContribution `json:"files"`        // largest first
+}
+
+// Analysis is the composition report of every bundle, largest first
+type Analysis struct {
+	Bundles []BundleAnalysis `json:"bundles"`
+}
+
+// Analyze reports the raw and approximate minified contribution of every source
+// file to each bundle. The minified share of a file is measured by minifying it
+// alone with the active options, so the sum is close to, not equal to, the size
+// of the bundle.
+func (c *AssetMin) Analyze() (*Analysis, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	m := c.minifier()
+	report := &Analysis{}
+	var errs []error
+
+	for _, fh := range c.assets() {
+		minified, err := fh.GetMinifiedContent(m)
+		if err != nil {
+			errs = append(errs, errors.New(fh.fileOutputName+" "+err.Error()))
+			continue
+		}
+
+		fh.mu.RLock()
+		bundle := BundleAnalysis{
+			Asset:        fh.fileOutputName,
+			MediaType:    fh.mediatype,
+			RawSize:      fh.bundleSize,
+			MinifiedSize: len(minified),
+		}
+		add := func(path string, content []byte) {
+			size := len(content)
+			if out, err := m.Bytes(fh.mediatype, content); err == nil {
+				size = len(out)
+			}
+			bundle.Files = append(bundle.Files, FileContribution{Path: path, RawSize: len(content), MinifiedSize: size})
+		}
+		if fh.initCode != nil {
+			if code, err := fh.initCode(); err == nil && code != "" {
+				add("(init code)", []byte(code))
+			}
+		}
+		for _, f := range fh.contentOpen {
+			add(f.path, f.content)
+		}
+		for _, f := range fh.contentMiddle {
+			if f.invalid != nil {
+				continue
+			}
+			content, err := fh.transformFile(f)
+			if err != nil {
+				content = f.content
+			}
+			add(f.path, content)
+		}
+		for _, f := range fh.contentClose {
+			add(f.path, f.content)
+		}
+		fh.mu.RUnlock()
+
+		sort.SliceStable(bundle.Files, func(i, j int) bool {
+			a, b := bundle.Files[i], bundle.Files[j]
+			if a.MinifiedSize != b.MinifiedSize {
+				return a.MinifiedSize > b.MinifiedSize
+			}
+			return a.RawSize > b.RawSize
+		})
+		report.Bundles = append(report.Bundles, bundle)
+	}
+
+	sort.SliceStable(report.Bundles, func(i, j int) bool {
+		return report.Bundles[i].MinifiedSize > report.Bundles[j].MinifiedSize
+	})
+	return report, errors.Join(errs...)
+}
+
+// treemapBox is one rectangle of the HTML treemap, positions in percent
+type treemapBox struct {
+	X, Y, W, H float64
+	Label      string
+	Title      string
+	Hue        int
+	Bundle     bool
+}
+
+// WriteHTML writes the report as a self-contained HTML page with a treemap of
+// the minified sizes and a table per bundle, no external resources are used.
+func (a *Analysis) WriteHTML(w io.Writer) error {
+	// the layout is computed for a 1600x900 area and scaled to percent
+	const width, height = 1600.0, 900.0
+
+	var boxes []treemapBox
+	values := make([]float64, len(a.Bundles))
+	for i, b := range a.Bundles {
+		values[i] = float64(b.MinifiedSize)
+	}
+	for i, r := range squarify(values, rect{0, 0, width, height}) {
+		b := a.Bundles[i]
+		hue := (i * 67) % 360
+		boxes = append(boxes, treemapBox{
+			X: r.x / width * 100, Y: r.y / height * 100, W: r.w / width * 100, H: r.h / height * 100,
+			Label: b.Asset, Title: fmt.Sprintf("%s %s minified", b.Asset, formatBytes(b.MinifiedSize)), Hue: hue, Bundle: true,
+		})
+
+		// the files share the bundle box below its label
+		const header = 24.0
+		inner := rect{r.x, r.y + header, r.w, r.h - header}
+		if inner.h <= 0 {
+			continue
+		}
+		fileValues := make([]float64, len(b.Files))
+		for j, f := range b.Files {
+			fileValues[j] = float64(f.MinifiedSize)
+		}
+		for j, fr := range squarify(fileValues, inner) {
+			f := b.Files[j]
+			boxes = append(boxes, treemapBox{
+				X: fr.x / width * 100, Y: fr.y / height * 100, W: fr.w / width * 100, H: fr.h / height * 100,
+				Label: f.Path, Title: fmt.Sprintf("%s\n%s minified, %s raw", f.Path, formatBytes(f.MinifiedSize), formatBytes(f.RawSize)), Hue: hue,
+			})
+		}
+	}
+
+	return analysisTemplate.Execute(w, struct {
+		Boxes   []treemapBox
+		Bundles []BundleAnalysis
+	}{boxes, a.Bundles})
+}
+
+func formatBytes(n int) string {
+	switch {
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(n)/(1<<10))
+	}
+	return fmt.Sprintf("%d B", n)
+}
+
+type rect struct{ x, y, w, h float64 }
+
+// squarify lays out values (largest first) in r with the squarified treemap
+// algorithm, returning one rectangle per value in the same order
+func squarify(values []float64, r rect) []rect {
+	out := make([]rect, 0, len(values))
+	var total float64
+	for _, v := range values {
+		total += max(v, 1)
+	}
+	if total == 0 {
+		return out
+	}
+
+	areas := make([]float64, len(values))
+	for i, v := range values {
+		areas[i] = max(v, 1) * r.w * r.h / total
+	}
+
+	for len(areas) > 0 {
+		side := min(r.w, r.h)
+		n := 1
+		for n < len(areas) && worstRatio(areas[:n+1], side) <= worstRatio(areas[:n], side) {
+			n++
+		}
+		var rowArea float64
+		for _, a := range areas[:n] {
+			rowArea += a
+		}
+
+		if r.w >= r.h {
+			// a column on the left side
+			colW := rowArea / r.h
+			y := r.y
+			for _, a := range areas[:n] {
+				out = append(out, rect{r.x, y, colW, a / colW})
+				y += a / colW
+			}
+			r.x, r.w = r.x+colW, r.w-colW
+		} else {
+			// a row on the top side
+			rowH := rowArea / r.w
+			x := r.x
+			for _, a := range areas[:n] {
+				out = append(out, rect{x, r.y, a / rowH, rowH})
+				x += a / rowH
+			}
+			r.y, r.h = r.y+rowH, r.h-rowH
+		}
+		areas = areas[n:]
+	}
+	return out
+}
+
+// worstRatio returns the worst aspect ratio of a row of areas laid along side
+func worstRatio(row []float64, side float64) float64 {
+	var sum, hi, lo float64
+	lo = row[0]
+	for _, a := range row {
+		sum += a
+		hi, lo = max(hi, a), min(lo, a)
+	}
+	s2, side2 := sum*sum, side*side
+	return max(side2*hi/s2, s2/(side2*lo))
+}
+
+var analysisTemplate = template.Must(template.New("analysis").Parse(`<!doctype html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>assetmin bundle analysis</title>
+<style>
+body{font:14px system-ui,sans-serif;margin:16px;color:#222}
+.map{position:relative;width:100%;height:70vh;border:1px solid #999}
+.box{position:absolute;box-sizing:border-box;overflow:hidden;border:1px solid #fff;padding:2px 4px;font-size:12px;white-space:nowrap;text-overflow:ellipsis}
+.bundle{font-weight:bold;font-size:14px;border-color:#333}
+table{border-collapse:collapse;margin:8px 0 24px}
+td,th{padding:2px 12px;text-align:right}
+td:first-child,th:first-child{text-align:left}
+</style>
+</head>
+<body>
+<h1>Bundle analysis</h1>
+<div class="map">
+{{- range .Boxes}}
+<div class="box{{if .Bundle}} bundle{{end}}" title="{{.Title}}" style="left:{{printf "%.3f" .X}}%;top:{{printf "%.3f" .Y}}%;width:{{printf "%.3f" .W}}%;height:{{printf "%.3f" .H}}%;background:hsl({{.Hue}},{{if .Bundle}}45%,80%{{else}}55%,65%{{end}})">{{.Label}}</div>
+{{- end}}
+</div>
+{{range .Bundles}}
+<h2>{{.Asset}} <small>{{.MediaType}}, {{.MinifiedSize}} bytes minified, {{.RawSize}} raw</small></h2>
+<table>
+<tr><th>file</th><th>minified</th><th>raw</th></tr>
+{{- range .Files}}
+<tr><td>{{.Path}}</td><td>{{.MinifiedSize}}</td><td>{{.RawSize}}</td></tr>
+{{- end}}
+</table>
+{{end}}
+</body>
+</html>
+`))
@@ -0,0 +1,91 @@
+package assetmin
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestAnalyze verifies the contribution of every source file to its bundle and
+// the JSON and HTML debug endpoints.
+func TestAnalyze(t *testing.T) {
+	env := setupTestEnv("analyze", t)
+	defer env.CleanDirectory()
+	am := env.AssetsHandler
+
+	files := map[string]string{
+		filepath.Join(env.ModulesDir, "a", "big.js"):   "function calculateEverything(firstArgument, secondArgument) { return firstArgument + secondArgument; }",
+		filepath.Join(env.ModulesDir, "b", "small.js"): "console.log('x');",
+		filepath.Join(env.ModulesDir, "b", "app.css"):  ".app { color: red; }",
+	}
+	for path, content := range files {
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+	_, err := am.ScanDirectories(env.ModulesDir)
+	require.NoError(t, err)
+
+	report, err := am.Analyze()
+	require.NoError(t, err)
+
+	bundles := map[string]BundleAnalysis{}
+	for i, b := range report.Bundles {
+		bundles[b.Asset] = b
+		if i > 0 {
+			require.LessOrEqual(t, b.MinifiedSize, report.Bundles[i-1].MinifiedSize, "bundles sorted by size")
+		}
+	}
+
+	js := bundles["script.js"]
+	require.Equal(t, "text/javascript", js.MediaType)
+	require.Equal(t, len(am.mainJsHandler.cachedMinified), js.MinifiedSize)
+	require.Equal(t, filepath.Join(env.ModulesDir, "a", "big.js"), js.Files[0].Path)
+	require.Less(t, js.Files[0].MinifiedSize, js.Files[0].RawSize)
+	require.Equal(t, filepath.Join(env.ModulesDir, "b", "small.js"), js.Files[1].Path)
+	require.Equal(t, "(init code)", js.Files[2].Path)
+
+	css := bundles["style.css"]
+	require.Equal(t, []FileContribution{{Path: filepath.Join(env.ModulesDir, "b", "app.css"), RawSize: 20, MinifiedSize: 15}}, css.Files)
+
+	var page bytes.Buffer
+	require.NoError(t, report.WriteHTML(&page))
+	require.Contains(t, page.String(), "big.js")
+	require.NotContains(t, page.String(), "<script src", "the report is self-contained")
+
+	mux := http.NewServeMux()
+	am.RegisterDebugRoutes(mux)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", DebugURLPrefix+"analysis.json", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	var served Analysis
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &served))
+	require.Len(t, served.Bundles, len(report.Bundles))
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", DebugURLPrefix+"analysis.html", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.True(t, strings.HasPrefix(rec.Body.String(), "<!doctype html>"))
+}
+
+func TestSquarify(t *testing.T) {
+	rects := squarify([]float64{6, 6, 4, 3, 2, 2, 1}, rect{0, 0, 6, 4})
+	require.Len(t, rects, 7)
+
+	var area float64
+	for _, r := range rects {
+		require.GreaterOrEqual(t, r.x, -1e-9)
+		require.LessOrEqual(t, r.x+r.w, 6+1e-9)
+		require.LessOrEqual(t, r.y+r.h, 4+1e-9)
+		area += r.w * r.h
+	}
+	require.InDelta(t, 24, area, 1e-9)
+	require.InDelta(t, 6, rects[0].w*rects[0].h, 1e-9)
+}
@@ -2,6 +2,7 @@ package assetmin
 
 import (
 	"bytes"
+	"errors"
 	"os"
 	"path/filepath"
 	"slices"
@@ -18,20 +19,44 @@ type asset struct {
 	urlPath        string                 // HTTP route path, e.g., "/assets/style.css" or "/style.css"
 	mediatype      string                 // eg: "text/html", "text/css", "image/svg+xml"
 	initCode       func() (string, error) // eg js: "console.log('hello world')". eg: css: "body{color:red}" eg: html: "<html></html>". eg: svg: "<svg></svg>"
+	ac             *Config
+	match          ignoreRules // source files taken by a custom asset, see RegisterAsset
+	separator      string      // written between the source files eg: "," in a JSON array, see AssetConfig
 
 	contentOpen   []*contentFile // eg: files from theme folder
 	contentMiddle []*contentFile //eg: files from modules folder
 	contentClose  []*contentFile // eg: files js from testin or end tags
 
-	mu             sync.RWMutex // Mutex for thread-safe access to the cache
-	cachedMinified []byte       // Minified content ready to serve
-	cacheValid     bool         // True if cache matches current content
+	mu             sync.RWMutex    // Mutex for thread-safe access to the cache
+	cachedMinified []byte          // Minified content ready to serve
+	cacheValid     bool            // True if cache matches current content
+	transformers   []Transformer   // processing hooks in registration order, see RegisterTransformer
+	sourceHash     string          // hash of the content cachedMinified was produced from
+	outputSum      string          // contentHash of cachedMinified, see outputHash
+	outputGzip     int             // gzip size of cachedMinified, see setOutput
+	bundleSize     int             // bytes of the last bundle before minification
+	fileSizes      map[string]int  // source file sizes of the last build, see checkBudget
+	prevFileSizes  map[string]int  // source file sizes of the build before
+	diagnostic     *Diagnostic     // error of the last build located in its source file, see Diagnostics
+	conflicts      map[string]bool // conflicts already logged, see reportConflicts
 }
 
 // contentFile represents a file with its path and content
 type contentFile struct {
-	path    string // eg: modules/module1/file.js
-	content []byte /// eg: "console.log('hello world')"
+	path    string      // eg: modules/module1/file.js
+	content []byte      /// eg: "console.log('hello world')"
+	invalid *Diagnostic // set when the file failed validation and is left out of the bundle
+	facts   *fileFacts  // what the file declares, see findConflicts
+	sum     string      // contentHash of content, see hash
+}
+
+// hash returns the contentHash of the file, computed on the first call since
+// the content of a contentFile is replaced with a new one, never modified
+func (f *contentFile) hash() string {
+	if f.sum == "" {
+		f.sum = contentHash(f.content)
+	}
+	return f.sum
 }
 
 // WriteToDisk writes the content file to disk at the specified path
@@ -54,6 +79,7 @@ func newAssetFile(outputName, mediaType string, ac *Config, initCode func() (str
 		outputPath:     filepath.Join(ac.OutputDir, outputName),
 		mediatype:      mediaType,
 		initCode:       initCode,
+		ac:             ac,
 		contentOpen:    []*contentFile{},
 		contentMiddle:  []*contentFile{},
 		contentClose:   []*contentFile{},
@@ -63,7 +89,7 @@ func newAssetFile(outputName, mediaType string, ac *Config, initCode func() (str
 }
 
 // assetHandlerFiles ej &mainJsHandler, &mainStyleCssHandler
-func (h *asset) UpdateContent(filePath, event string, f *contentFile) (err error) {
+func (h *asset) UpdateContent(filePath string, kind EventKind, f *contentFile) (err error) {
 	h.InvalidateCache()
 	// por defecto los archivos de destino son contenido comun eg: modulos, archivos sueltos
 	filesToUpdate := &h.contentMiddle
@@ -79,78 +105,78 @@ func (h *asset) UpdateContent(filePath, event string, f *contentFile) (err error
 		}
 	}
 
-	switch event {
-	case "create", "write", "modify":
+	switch kind {
+	case EventCreate, EventWrite:
 
 		if idx := findFileIndex(*filesToUpdate, filePath); idx != -1 {
 			// Exact path exists: replace content
 			(*filesToUpdate)[idx] = f
+		} else if idx := h.findSameContent(f.content); idx != -1 {
+			// Legacy rename flow (opt-in): the rename event for the old file was
+			// ignored and the create for the new file carries the same content,
+			// reuse the existing entry instead of creating a duplicate.
+			(*filesToUpdate)[idx] = f
 		} else {
-			// File with this path not found. This can happen in a rename flow where
-			// a rename event is sent for the old file and a create event for the
-			// new file arrives afterwards. Instead of blindly appending and
-			// creating a duplicate, try to detect if this new file corresponds
-			// to an existing memory entry (rename case) by comparing content.
-			replaced := false
-			for i, existing := range *filesToUpdate {
-				if bytes.Equal(existing.content, f.content) {
-					// Reuse existing entry: update its path and content
-					(*filesToUpdate)[i].path = filePath
-					(*filesToUpdate)[i].content = f.content
-					replaced = true
-					break
-				}
-			}
-			if !replaced {
-				// No match found: append as new file
-				*filesToUpdate = append(*filesToUpdate, f)
-			}
+			*filesToUpdate = append(*filesToUpdate, f)
 		}
-	case "rename":
-	case "remove", "delete":
+	case EventRename:
+	case EventRemove:
 		if idx := findFileIndex(*filesToUpdate, filePath); idx != -1 {
 			*filesToUpdate = slices.Delete((*filesToUpdate), idx, idx+1)
 		}
+	default:
+		return errors.New("UpdateContent " + ErrUnknownEvent.Error())
 	}
 
 	return
 }
 
-func findFileIndex(files []*contentFile, filePath string) int {
-	for i, f := range files {
-		if f.path == filePath {
-			return i
-		}
+// RenameContent moves the entry of oldPath to newPath keeping its position in the
+// bundle and replaces its content. It returns false when oldPath is not loaded.
+func (h *asset) RenameContent(oldPath, newPath string, f *contentFile) bool {
+	idx := findFileIndex(h.contentMiddle, oldPath)
+	if idx == -1 {
+		return false
 	}
-	return -1
-}
+	if strings.HasSuffix(h.fileOutputName, ".html") && isCompleteHtmlDocument(string(f.content)) {
+		// renamed into a template, it isn't a module anymore
+		return false
+	}
+	h.InvalidateCache()
 
-// WriteContent processes the asset content and writes it to the provided buffer
-func (h *asset) WriteContent(buf *bytes.Buffer) {
-	if h.initCode != nil {
-		initCode, err := h.initCode()
-		if err == nil {
-			buf.WriteString(initCode)
+	// a stale entry already loaded under the new path is replaced by the moved one
+	if dup := findFileIndex(h.contentMiddle, newPath); dup != -1 && dup != idx {
+		h.contentMiddle = slices.Delete(h.contentMiddle, dup, dup+1)
+		if dup < idx {
+			idx--
 		}
 	}
 
-	// Write open content first
-	for _, f := range h.contentOpen {
-		buf.Write(f.content)
-		buf.WriteString("\n") // Add newline between files
-	}
+	h.contentMiddle[idx] = f
+	return true
+}
 
-	// Then write middle content files
-	for _, f := range h.contentMiddle {
-		buf.Write(f.content)
-		buf.WriteString("\n") // Add newline between files
+// findSameContent returns the index of the entry with the same content, only
+// when the content based rename detection is enabled with Config.RenameByContent
+func (h *asset) findSameContent(content []byte) int {
+	if h.ac == nil || !h.ac.RenameByContent {
+		return -1
 	}
+	for i, existing := range h.contentMiddle {
+		if bytes.Equal(existing.content, content) {
+			return i
+		}
+	}
+	return -1
+}
 
-	// Then write close content files
-	for _, f := range h.contentClose {
-		buf.Write(f.content)
-		buf.WriteString("\n") // Add newline between files
+func findFileIndex(files []*contentFile, filePath string) int {
+	for i, f := range files {
+		if f.path == filePath {
+			return i
+		}
 	}
+	return -1
 }
 
 // InvalidateCache marks the asset's cache as invalid.
@@ -167,27 +193,96 @@ func (h *asset) RegenerateCache(minifier *minify.M) error {
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
-	var buf bytes.Buffer
-	h.WriteContent(&buf)
+	return h.rebuild(minifier)
+}
 
-	minified, err := minifier.Bytes(h.mediatype, buf.Bytes())
+// rebuild minifies the current content into the cache. When the content is the
+// same that produced the cached result, eg: a restored snapshot or a write event
+// that didn't change anything, the previous minification is reused.
+// The caller must hold the write lock.
+func (h *asset) rebuild(minifier *minify.M) error {
+	bundle, segments, err := h.bundleSegments()
 	if err != nil {
 		return err
 	}
+	h.bundleSize = len(bundle)
+	h.diagnostic = nil
 
-	h.cachedMinified = minified
+	hash := contentHash(bundle)
+	if h.sourceHash != "" && h.sourceHash == hash {
+		h.cacheValid = true
+		return nil
+	}
+
+	minified, err := h.minifyBundle(minifier, bundle, segments)
+	if err != nil {
+		errors.As(err, &h.diagnostic)
+		return err
+	}
+
+	h.setOutput(minified)
+	h.sourceHash = hash
 	h.cacheValid = true
 	return nil
 }
 
+// minify returns the output of the current content with minifier without
+// touching the cache. The caller must hold the lock.
+func (h *asset) minify(minifier *minify.M) ([]byte, error) {
+	bundle, segments, err := h.bundleSegments()
+	if err != nil {
+		return nil, err
+	}
+	return h.minifyBundle(minifier, bundle, segments)
+}
+
+// minifyBundle minifies bundle and applies the AfterMinify hooks, a parse error
+// is returned as a *Diagnostic located with segments
+func (h *asset) minifyBundle(minifier *minify.M, bundle []byte, segments []segment) ([]byte, error) {
+	minified, err := minifier.Bytes(h.mediatype, bundle)
+	if errors.Is(err, minify.ErrNotExist) {
+		// no minifier for this media type eg: a custom data.json, serve it as is
+		minified, err = bundle, nil
+	}
+	if err != nil {
+		return nil, h.diagnose(err, segments)
+	}
+	return h.afterMinify(minified)
+}
+
+// setOutput caches minified with its hash and gzip size, computed once per build
+// for the ETag, the manifest, the size budget and the metrics.
+// The caller must hold the write lock.
+func (h *asset) setOutput(minified []byte) {
+	h.cachedMinified = minified
+	h.outputSum = contentHash(minified)
+	h.outputGzip = gzipSize(minified)
+}
+
+// outputHash returns the hash of the cached minified content, computed once per
+// build, empty when nothing was built yet. The caller must hold the lock.
+func (h *asset) outputHash() string {
+	if h.cachedMinified == nil {
+		return ""
+	}
+	return h.outputSum
+}
+
 // GetMinifiedContent returns the minified content of the asset, regenerating the cache if necessary.
 // It uses a double-checked locking pattern with a read-write mutex for thread-safe access.
 func (h *asset) GetMinifiedContent(minifier *minify.M) ([]byte, error) {
+	content, _, _, err := h.minifiedOutput(minifier)
+	return content, err
+}
+
+// minifiedOutput is GetMinifiedContent returning the outputHash and the gzip
+// size of the content too, all from the same build
+func (h *asset) minifiedOutput(minifier *minify.M) (content []byte, hash string, gzipSize int, err error) {
 	// First, try with a read lock to check if the cache is valid.
 	h.mu.RLock()
 	if h.cacheValid {
 		defer h.mu.RUnlock()
-		return h.cachedMinified, nil
+		return h.cachedMinified, h.outputSum, h.outputGzip, nil
 	}
 	h.mu.RUnlock()
 
@@ -197,20 +292,13 @@ func (h *asset) GetMinifiedContent(minifier *minify.M) ([]byte, error) {
 	// It's possible another goroutine regenerated the cache while we were waiting for the write lock.
 	// So, we need to double-check if the cache is still invalid.
 	if h.cacheValid {
-		return h.cachedMinified, nil
+		return h.cachedMinified, h.outputSum, h.outputGzip, nil
 	}
 
-	var buf bytes.Buffer
-	h.WriteContent(&buf)
-
-	minified, err := minifier.Bytes(h.mediatype, buf.Bytes())
-	if err != nil {
-		return nil, err
+	if err := h.rebuild(minifier); err != nil {
+		return nil, "", 0, err
 	}
-
-	h.cachedMinified = minified
-	h.cacheValid = true
-	return h.cachedMinified, nil
+	return h.cachedMinified, h.outputSum, h.outputGzip, nil
 }
 
 // URLPath returns the URL path for the asset.
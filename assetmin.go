@@ -1,16 +1,16 @@
 package assetmin
 
 import (
+	"io/fs"
+	"log/slog"
 	"os"
 	"path"
-	"regexp"
+	"slices"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/tdewolff/minify/v2"
-	"github.com/tdewolff/minify/v2/css"
-	"github.com/tdewolff/minify/v2/html"
-	"github.com/tdewolff/minify/v2/js"
-	"github.com/tdewolff/minify/v2/svg"
 )
 
 type WorkMode int
@@ -28,28 +28,59 @@ type AssetMin struct {
 	spriteSvgHandler    *asset
 	faviconSvgHandler   *asset
 	indexHtmlHandler    *asset
-	min                 *minify.M
-	workMode            WorkMode // Current work mode
+	min                 atomic.Pointer[minify.M] // active minifier, devMin or prodMin by work mode
+	devMin              *minify.M                // MemoryMode, Config.MinifyDev
+	prodMin             *minify.M                // DiskMode and Export, Config.MinifyProd
+	workMode            WorkMode                 // Current work mode
+	watcher             *watcher                 // built-in polling watcher, nil when not running
+	ignore              *ignoreSet
+	metrics             metrics      // counters exposed by Metrics
+	subs                subscribers  // receivers of AssetEvent, see Subscribe
+	customAssets        []*asset     // added with RegisterAsset
+	legacyLog           *slog.Logger // adapter writing to Config.Logger
+	written             Manifest     // outputs written in DiskMode, see writeManifest
 }
 
 type Config struct {
 	OutputDir               string                 // eg: web/static, web/public, web/assets
 	Logger                  func(message ...any)   // Renamed from io.Writer to a function type
+	Slog                    *slog.Logger           // structured logger, takes precedence over Logger
+	LogLevel                slog.Level             // minimum level written to Logger (default: slog.LevelInfo, Slog filters its own)
 	GetRuntimeInitializerJS func() (string, error) // javascript code to initialize the wasm or other handlers
 	AppName                 string                 // Application name for templates (default: "MyApp")
 	AssetsURLPrefix         string                 // New: for HTTP routes
+	SourceDirs              []string               // source roots monitored by StartWatcher eg: web/theme, modules
+	WatchInterval           time.Duration          // polling interval of the built-in watcher (default: 500ms)
+	IgnorePatterns          []string               // gitignore style patterns of source files to skip eg: *.test.js, node_modules/, drafts/
+	IgnoreFiles             []string               // source files to skip by exact path eg: a runtime initializer kept in a source root
+	OutputFS                OutputFS               // filesystem for DiskMode writes (default: OSFS, use NewMemFS in tests)
+	FileMode                fs.FileMode            // mode of written files (default: 0 keeps the existing mode, 0644 for new files)
+	RenameByContent         bool                   // opt-in fallback: a create whose content equals an existing entry replaces it as a rename (prefer RenameFile)
+	MinifyDev               *MinifyOptions         // minifier options in MemoryMode (default: see MinifyOptions)
+	MinifyProd              *MinifyOptions         // minifier options in DiskMode and Export (default: see MinifyOptions)
+	SizeBudgets             map[string]SizeBudget  // size limits by output name eg: "script.js": {Gzip: 50 << 10}
+	StrictBudgets           bool                   // CI mode: an exceeded budget fails the build and Export instead of logging a warning
 }
 
 func NewAssetMin(ac *Config) *AssetMin {
 	c := &AssetMin{
 		Config: ac,
-		min:    minify.New(),
+		ignore: newIgnoreSet(ac.IgnorePatterns, ac.IgnoreFiles),
+	}
+	c.legacyLog = slog.New(&loggerHandler{cfg: ac})
+
+	for _, root := range ac.SourceDirs {
+		c.ignore.addRoot(root)
 	}
 
 	if c.AppName == "" {
 		c.AppName = "MyApp"
 	}
 
+	if c.OutputFS == nil {
+		c.OutputFS = OSFS{}
+	}
+
 	jsMainFileName := "script.js"
 	cssMainFileName := "style.css"
 	svgMainFileName := "sprite.svg"
@@ -58,6 +89,7 @@ func NewAssetMin(ac *Config) *AssetMin {
 
 	c.mainStyleCssHandler = newAssetFile(cssMainFileName, "text/css", ac, nil)
 	c.mainJsHandler = newAssetFile(jsMainFileName, "text/javascript", ac, ac.GetRuntimeInitializerJS)
+	c.mainJsHandler.addTransformer(useStrictTransformer)
 	c.spriteSvgHandler = NewSvgHandler(ac, svgMainFileName)
 	c.faviconSvgHandler = NewFaviconSvgHandler(ac, svgFaviconFileName)
 
@@ -69,30 +101,43 @@ func NewAssetMin(ac *Config) *AssetMin {
 
 	c.indexHtmlHandler = NewHtmlHandler(ac, htmlMainFileName, c.mainStyleCssHandler.URLPath(), c.mainJsHandler.URLPath())
 	c.indexHtmlHandler.urlPath = "/" // Index is always at root
-	c.min.Add("text/html", &html.Minifier{
-		KeepDocumentTags: true,
-		KeepEndTags:      true,
-		KeepWhitespace:   true,
-		KeepQuotes:       true,
-	})
 
-	c.min.AddFunc("text/css", css.Minify)
-	c.min.AddFuncRegexp(regexp.MustCompile("^(application|text)/(x-)?(java|ecma)script$"), js.Minify)
-	c.min.AddFunc("image/svg+xml", svg.Minify)
+	for _, o := range []**MinifyOptions{&c.MinifyDev, &c.MinifyProd} {
+		if err := (*o).Validate(); err != nil {
+			c.log().Warn("invalid minifier options, using the defaults", LogKeyError, err)
+			*o = nil
+		}
+	}
+	c.initMinifiers()
+	c.min.Store(c.devMin)
 
 	c.mainJsHandler.initCode = c.startCodeJS
 
 	return c
 }
 
+// SupportedExtensions returns the source extensions handled by the built-in
+// assets plus the ones of the assets added with RegisterAsset
 func (c *AssetMin) SupportedExtensions() []string {
-	return []string{".js", ".css", ".svg", ".html"}
+	exts := []string{".js", ".css", ".svg", ".html"}
+	for _, ext := range c.customExtensions() {
+		if !slices.Contains(exts, ext) {
+			exts = append(exts, ext)
+		}
+	}
+	return exts
 }
 
-func (c *AssetMin) writeMessage(messages ...any) {
-	if c.Logger != nil {
-		c.Logger(messages...)
+// assets returns every asset handler in build order
+func (c *AssetMin) assets() []*asset {
+	out := []*asset{
+		c.mainStyleCssHandler,
+		c.mainJsHandler,
+		c.spriteSvgHandler,
+		c.faviconSvgHandler,
 	}
+	out = append(out, c.customAssets...)
+	return append(out, c.indexHtmlHandler)
 }
 
 func fileExists(path string) string {
@@ -105,8 +150,8 @@ func fileExists(path string) string {
 
 func (c *AssetMin) EnsureOutputDirectoryExists() {
 	outputDir := c.OutputDir
-	if err := os.MkdirAll(outputDir, 0755); err != nil {
-		c.writeMessage("dont create output dir", err)
+	if err := c.OutputFS.MkdirAll(outputDir, 0755); err != nil {
+		c.log().Error("can't create the output dir", LogKeyPath, outputDir, LogKeyError, err)
 	}
 }
 
@@ -124,8 +169,8 @@ func (c *AssetMin) RefreshAsset(extension string) {
 	}
 
 	if fh != nil {
-		if err := c.processAsset(fh); err != nil {
-			c.writeMessage("Error refreshing asset "+extension, err)
+		if err := c.processAsset(fh, ""); err != nil {
+			c.log().Error("refreshing asset", LogKeyAsset, fh.fileOutputName, LogKeyError, err)
 		}
 	}
 }
@@ -135,6 +180,7 @@ func (c *AssetMin) SetWorkMode(mode WorkMode) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 	c.workMode = mode
+	c.useMinifier()
 }
 
 // GetWorkMode returns the current work mode of AssetMin.
@@ -0,0 +1,120 @@
+package assetmin
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ErrBudgetExceeded is wrapped by the errors of assets over their SizeBudget
+var ErrBudgetExceeded = errors.New("size budget exceeded")
+
+// SizeBudget limits the size in bytes of an asset, 0 disables a limit
+type SizeBudget struct {
+	Raw      int // bundle before minification
+	Minified int // minified output
+	Gzip     int // minified output compressed with gzip
+}
+
+// growthReportSize is how many files the budget message names
+const growthReportSize = 3
+
+// recordFileSizes keeps the source file sizes of the build that just finished
+// and of the previous one, used to report what grew. The caller must hold the
+// write lock of fh.
+func (fh *asset) recordFileSizes() {
+	fh.prevFileSizes = fh.fileSizes
+	fh.fileSizes = make(map[string]int, len(fh.contentMiddle))
+	for _, f := range fh.contentMiddle {
+		if f.invalid != nil {
+			continue // quarantined files aren't in the bundle
+		}
+		fh.fileSizes[f.path] = len(f.content)
+	}
+}
+
+// checkBudget compares the sizes of a build of fh with Config.SizeBudgets and
+// returns an error naming the exceeded limits and the files that grew the most
+// since the previous build. It takes the read lock of fh for the file sizes.
+func (c *AssetMin) checkBudget(fh *asset, rawSize, minifiedSize, gzipSize int) error {
+	budget, ok := c.SizeBudgets[fh.fileOutputName]
+	if !ok {
+		return nil
+	}
+
+	var over []string
+	check := func(kind string, size, limit int) {
+		if limit > 0 && size > limit {
+			over = append(over, kind+" "+strconv.Itoa(size)+" > "+strconv.Itoa(limit))
+		}
+	}
+	check("raw", rawSize, budget.Raw)
+	check("minified", minifiedSize, budget.Minified)
+	check("gzip", gzipSize, budget.Gzip)
+	if len(over) == 0 {
+		return nil
+	}
+
+	msg := fh.fileOutputName + " " + strings.Join(over, ", ") + " bytes"
+	fh.mu.RLock()
+	grown := growth(fh.prevFileSizes, fh.fileSizes)
+	fh.mu.RUnlock()
+	if len(grown) > 0 {
+		msg += "; grew most: " + strings.Join(grown, ", ")
+	}
+	return fmt.Errorf("%w: %s", ErrBudgetExceeded, msg)
+}
+
+// growth returns the files that grew the most between two builds eg: "app.js +1200",
+// none without a previous build since every file would count as grown
+func growth(previous, current map[string]int) []string {
+	if previous == nil {
+		return nil
+	}
+	type delta struct {
+		path string
+		diff int
+	}
+	var deltas []delta
+	for path, size := range current {
+		if diff := size - previous[path]; diff > 0 {
+			deltas = append(deltas, delta{path, diff})
+		}
+	}
+	sort.Slice(deltas, func(i, j int) bool {
+		if deltas[i].diff != deltas[j].diff {
+			return deltas[i].diff > deltas[j].diff
+		}
+		return deltas[i].path < deltas[j].path
+	})
+
+	var out []string
+	for i, d := range deltas {
+		if i == growthReportSize {
+			break
+		}
+		entry := d.path + " +" + strconv.Itoa(d.diff)
+		if _, existed := previous[d.path]; !existed {
+			entry += " (new)"
+		}
+		out = append(out, entry)
+	}
+	return out
+}
+
+// enforceBudget handles a budget error: returned in strict mode, logged as a
+// warning otherwise. Strict mode stops the output from being written or
+// exported; in MemoryMode there is nothing to write, so the build over budget
+// is still served while the event returns the error.
+func (c *AssetMin) enforceBudget(fh *asset, err error) error {
+	if err == nil {
+		return nil
+	}
+	if c.StrictBudgets {
+		return err
+	}
+	c.log().Warn(err.Error(), LogKeyAsset, fh.fileOutputName)
+	return nil
+}
@@ -0,0 +1,90 @@
+package assetmin
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestSizeBudgets verifies the warning and strict modes and that the message
+// names the files that grew the most since the previous build.
+func TestSizeBudgets(t *testing.T) {
+	env := setupTestEnv("size_budgets", t)
+	env.AssetsHandler.SetWorkMode(DiskMode)
+	defer env.CleanDirectory()
+	am := env.AssetsHandler
+
+	var warnings []string
+	am.Logger = func(message ...any) {
+		if line := fmt.Sprintln(message...); strings.HasPrefix(line, "warn:") {
+			warnings = append(warnings, line)
+		}
+	}
+	am.SizeBudgets = map[string]SizeBudget{"style.css": {Minified: 40, Gzip: 1000}}
+
+	write := func(name, content string) string {
+		path := filepath.Join(env.ModulesDir, "budget", name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+		return path
+	}
+
+	small := write("small.css", ".a { color: red; }")
+	require.NoError(t, am.NewFileEventKind("small.css", ".css", small, EventCreate))
+	require.Empty(t, warnings)
+
+	big := write("big.css", ".big-selector-name { color: blue; margin: 0; }")
+	require.NoError(t, am.NewFileEventKind("big.css", ".css", big, EventCreate))
+	require.Len(t, warnings, 1)
+	require.Contains(t, warnings[0], "style.css minified")
+	require.Contains(t, warnings[0], "> 40 bytes")
+	require.Contains(t, warnings[0], "grew most: "+big)
+	require.NotContains(t, warnings[0], small)
+	require.FileExists(t, env.MainCssPath)
+
+	t.Run("strict_mode_fails", func(t *testing.T) {
+		am.StrictBudgets = true
+		defer func() { am.StrictBudgets = false }()
+
+		before, err := os.ReadFile(env.MainCssPath)
+		require.NoError(t, err)
+
+		write("small.css", ".a { color: red; padding: 10px; }")
+		err = am.NewFileEventKind("small.css", ".css", small, EventWrite)
+		require.ErrorIs(t, err, ErrBudgetExceeded)
+		require.Contains(t, err.Error(), "grew most: "+small)
+
+		after, err := os.ReadFile(env.MainCssPath)
+		require.NoError(t, err)
+		require.Equal(t, before, after, "an asset over budget is not written in strict mode")
+
+		_, err = am.Export(filepath.Join(env.BaseDir, "dist"), ExportOptions{})
+		require.ErrorIs(t, err, ErrBudgetExceeded)
+		require.Equal(t, uint64(1), am.Metrics()["style.css"].BudgetErrors)
+	})
+
+	t.Run("strict_memory_mode_serves", func(t *testing.T) {
+		am.StrictBudgets = true
+		am.SetWorkMode(MemoryMode)
+		defer func() {
+			am.StrictBudgets = false
+			am.SetWorkMode(DiskMode)
+		}()
+
+		write("big.css", ".big-selector-name { color: green; margin: 0; }")
+		require.ErrorIs(t, am.NewFileEventKind("big.css", ".css", big, EventWrite), ErrBudgetExceeded)
+		content, err := am.mainStyleCssHandler.GetMinifiedContent(am.minifier())
+		require.NoError(t, err)
+		require.Contains(t, string(content), "green", "nothing is written in MemoryMode, the build is served")
+	})
+
+	t.Run("growth_order", func(t *testing.T) {
+		grown := growth(map[string]int{"a": 10, "b": 10}, map[string]int{"a": 15, "b": 40, "c": 5, "d": 1})
+		require.Equal(t, []string{"b +30", "a +5", "c +5 (new)"}, grown)
+		require.Empty(t, growth(nil, map[string]int{"a": 10}), "a first build has nothing to compare with")
+	})
+}
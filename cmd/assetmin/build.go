@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/tinywasm/assetmin"
+)
+
+// build scans the sources, writes every asset in DiskMode and prints a size report.
+// It exits with 1 when any file can't be read or minified.
+func build(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("build", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+
+	var common commonFlags
+	common.register(fs)
+	verbose := fs.Bool("v", false, "log every processed file")
+	var budgets budgetList
+	fs.Var(&budgets, "budget", "size budget in bytes as asset:kind=size, kind is raw, minified or gzip eg: script.js:gzip=51200, repeatable")
+	strict := fs.Bool("strict", false, "fail when an asset exceeds its size budget instead of warning (CI mode)")
+	report := fs.String("report", "", "directory where the bundle analysis is written as analysis.json and analysis.html")
+
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	level := slog.LevelWarn
+	if *verbose {
+		level = slog.LevelDebug
+	}
+
+	config := common.config(nil)
+	config.Slog = slog.New(slog.NewTextHandler(stderr, &slog.HandlerOptions{Level: level}))
+	config.SizeBudgets = budgets.budgets
+	config.StrictBudgets = *strict
+
+	am := assetmin.NewAssetMin(config)
+	am.SetWorkMode(assetmin.DiskMode)
+	am.EnsureOutputDirectoryExists()
+
+	if _, err := am.ScanDirectories(common.sources...); err != nil {
+		fmt.Fprintln(stderr, "assetmin build:", err)
+		return 1
+	}
+
+	manifest, err := am.Manifest()
+	if err != nil {
+		fmt.Fprintln(stderr, "assetmin build:", err)
+		return 1
+	}
+
+	printSizeReport(stdout, manifest)
+
+	if *report != "" {
+		if err := writeAnalysis(am, *report); err != nil {
+			fmt.Fprintln(stderr, "assetmin build:", err)
+			return 1
+		}
+	}
+	return 0
+}
+
+// writeAnalysis writes the bundle composition report to dir as JSON and HTML
+func writeAnalysis(am *assetmin.AssetMin, dir string) error {
+	analysis, err := am.Analyze()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(analysis, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, "analysis.json"), data, 0644); err != nil {
+		return err
+	}
+
+	var page bytes.Buffer
+	if err := analysis.WriteHTML(&page); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, "analysis.html"), page.Bytes(), 0644)
+}
+
+// budgetList is a repeatable -budget flag filling Config.SizeBudgets
+type budgetList struct {
+	budgets map[string]assetmin.SizeBudget
+	values  []string
+}
+
+func (l *budgetList) String() string {
+	return strings.Join(l.values, ",")
+}
+
+// Set parses asset:kind=size eg: script.js:gzip=51200
+func (l *budgetList) Set(value string) error {
+	target, size, ok := strings.Cut(value, "=")
+	name, kind, ok2 := strings.Cut(target, ":")
+	bytes, err := strconv.Atoi(size)
+	if !ok || !ok2 || name == "" || err != nil || bytes <= 0 {
+		return fmt.Errorf("invalid budget %q, expected asset:kind=bytes eg: script.js:gzip=51200", value)
+	}
+
+	if l.budgets == nil {
+		l.budgets = map[string]assetmin.SizeBudget{}
+	}
+	budget := l.budgets[name]
+	switch kind {
+	case "raw":
+		budget.Raw = bytes
+	case "minified":
+		budget.Minified = bytes
+	case "gzip":
+		budget.Gzip = bytes
+	default:
+		return fmt.Errorf("invalid budget kind %q, expected raw, minified or gzip", kind)
+	}
+	l.budgets[name] = budget
+	l.values = append(l.values, value)
+	return nil
+}
+
+// printSizeReport prints the minified and gzip size of every output
+func printSizeReport(w io.Writer, manifest assetmin.Manifest) {
+	names := make([]string, 0, len(manifest))
+	for name := range manifest {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "asset\tsize\tgzip\t")
+
+	var total, totalGzip int
+	for _, name := range names {
+		entry := manifest[name]
+		total += entry.Size
+		totalGzip += entry.GzipSize
+		fmt.Fprintf(tw, "%s\t%s\t%s\t\n", name, formatSize(entry.Size), formatSize(entry.GzipSize))
+	}
+	fmt.Fprintf(tw, "total\t%s\t%s\t\n", formatSize(total), formatSize(totalGzip))
+	tw.Flush()
+}
+
+func formatSize(n int) string {
+	if n < 1024 {
+		return fmt.Sprintf("%d B", n)
+	}
+	return fmt.Sprintf("%.1f KB", float64(n)/1024)
+}
@@ -0,0 +1,102 @@
+// Command assetmin bundles and minifies web assets without writing a Go program.
+//
+// Usage:
+//
+//	assetmin build -src web/theme -src modules -out web/public [-prefix /assets/] [-app MyApp] [-budget script.js:gzip=51200 -strict] [-report dir]
+//	assetmin serve -src web/theme -src modules [-addr localhost:8080] [-init wasm_exec.js]
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/tinywasm/assetmin"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run executes the command in args and returns the process exit code
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		usage(stderr)
+		return 2
+	}
+
+	switch args[0] {
+	case "build":
+		return build(args[1:], stdout, stderr)
+	case "serve":
+		return serve(args[1:], stdout, stderr)
+	case "help", "-h", "-help", "--help":
+		usage(stdout)
+		return 0
+	default:
+		fmt.Fprintf(stderr, "assetmin: unknown command %q\n\n", args[0])
+		usage(stderr)
+		return 2
+	}
+}
+
+func usage(w io.Writer) {
+	fmt.Fprint(w, `Usage: assetmin <command> [flags]
+
+Commands:
+  build   scan the source roots and write every asset to the output dir
+  serve   serve the assets from memory and rebuild them when the sources change
+
+Run "assetmin <command> -h" for the flags of a command.
+`)
+}
+
+// commonFlags are the Config fields shared by every command
+type commonFlags struct {
+	sources stringList
+	ignore  stringList
+	out     string
+	prefix  string
+	app     string
+}
+
+func (f *commonFlags) register(fs *flag.FlagSet) {
+	fs.Var(&f.sources, "src", "source root to scan, repeatable or comma separated (default: current dir)")
+	fs.Var(&f.ignore, "ignore", "gitignore style pattern of source files to skip, repeatable")
+	fs.StringVar(&f.out, "out", "web/public", "output directory")
+	fs.StringVar(&f.prefix, "prefix", "", "URL prefix of the assets eg: /assets/")
+	fs.StringVar(&f.app, "app", "", "application name used in templates")
+}
+
+// config maps the flags to the library Config
+func (f *commonFlags) config(logger func(message ...any)) *assetmin.Config {
+	if len(f.sources) == 0 {
+		f.sources = stringList{"."}
+	}
+	return &assetmin.Config{
+		OutputDir:       f.out,
+		Logger:          logger,
+		AppName:         f.app,
+		AssetsURLPrefix: f.prefix,
+		SourceDirs:      f.sources,
+		IgnorePatterns:  f.ignore,
+	}
+}
+
+// stringList is a flag that can be repeated or hold comma separated values
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			*l = append(*l, v)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tinywasm/assetmin"
+)
+
+func writeSources(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+	return dir
+}
+
+func TestBuildCommand(t *testing.T) {
+	t.Run("writes_assets_and_reports_sizes", func(t *testing.T) {
+		src := writeSources(t, map[string]string{
+			"modules/app.js":      "console.log('built by cli');",
+			"modules/app.css":     ".app { color: red; }",
+			"modules/app.test.js": "console.log('skipped');",
+		})
+		out := filepath.Join(t.TempDir(), "public")
+
+		var stdout, stderr bytes.Buffer
+		code := run([]string{"build", "-src", src, "-out", out, "-prefix", "/assets/", "-ignore", "*.test.js"}, &stdout, &stderr)
+		require.Equal(t, 0, code, stderr.String())
+
+		js, err := os.ReadFile(filepath.Join(out, "script.js"))
+		require.NoError(t, err)
+		require.Contains(t, string(js), "built by cli")
+		require.NotContains(t, string(js), "skipped")
+
+		css, err := os.ReadFile(filepath.Join(out, "style.css"))
+		require.NoError(t, err)
+		require.Equal(t, ".app{color:red}", string(css))
+
+		index, err := os.ReadFile(filepath.Join(out, "index.html"))
+		require.NoError(t, err)
+		require.Contains(t, string(index), `src="/assets/script.js"`)
+
+		report := stdout.String()
+		require.Contains(t, report, "script.js")
+		require.Contains(t, report, "style.css")
+		require.Contains(t, report, "total")
+	})
+
+	t.Run("minify_error_exits_non_zero", func(t *testing.T) {
+		src := writeSources(t, map[string]string{
+			"broken.js": "function (",
+		})
+
+		var stdout, stderr bytes.Buffer
+		code := run([]string{"build", "-src", src, "-out", filepath.Join(t.TempDir(), "public")}, &stdout, &stderr)
+		require.Equal(t, 1, code)
+		require.Contains(t, stderr.String(), "script.js")
+	})
+
+	t.Run("writes_analysis_report", func(t *testing.T) {
+		src := writeSources(t, map[string]string{"app.js": "console.log('analyzed');"})
+		reportDir := filepath.Join(t.TempDir(), "report")
+
+		var stdout, stderr bytes.Buffer
+		code := run([]string{"build", "-src", src, "-out", filepath.Join(t.TempDir(), "public"), "-report", reportDir}, &stdout, &stderr)
+		require.Equal(t, 0, code, stderr.String())
+
+		data, err := os.ReadFile(filepath.Join(reportDir, "analysis.json"))
+		require.NoError(t, err)
+		require.Contains(t, string(data), "app.js")
+		require.FileExists(t, filepath.Join(reportDir, "analysis.html"))
+	})
+
+	t.Run("unknown_command", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		require.Equal(t, 2, run([]string{"deploy"}, &stdout, &stderr))
+		require.Contains(t, stderr.String(), "unknown command")
+	})
+}
+
+func TestServeCommand(t *testing.T) {
+	src := writeSources(t, map[string]string{
+		"theme/theme.css": ".theme { color: blue; }",
+	})
+	initFile := filepath.Join(t.TempDir(), "init.js")
+	require.NoError(t, os.WriteFile(initFile, []byte("console.log('runtime init');"), 0644))
+
+	common := commonFlags{sources: stringList{src}, prefix: "/assets/"}
+	config := common.config(func(message ...any) { t.Log(message...) })
+	config.WatchInterval = 10 * time.Millisecond
+
+	am, handler, err := newServer(config, initFile, "")
+	require.NoError(t, err)
+	require.NoError(t, am.StartWatcher())
+	defer am.StopWatcher()
+
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	get := func(path string) string {
+		resp, err := http.Get(server.URL + path)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return string(body)
+	}
+
+	require.Contains(t, get("/"), `href="/assets/style.css"`)
+	require.Contains(t, get("/assets/script.js"), "runtime init")
+	require.Equal(t, ".theme{color:blue}", get("/assets/style.css"))
+
+	// changes in the source roots are rebuilt and served from memory
+	require.NoError(t, os.WriteFile(filepath.Join(src, "theme", "theme.css"), []byte(".theme { color: red; }"), 0644))
+	require.Eventually(t, func() bool {
+		return get("/assets/style.css") == ".theme{color:red}"
+	}, 2*time.Second, 10*time.Millisecond)
+
+	_, _, err = newServer(common.config(nil), filepath.Join(src, "missing.js"), "")
+	require.Error(t, err)
+}
+
+// TestServeInitInsideRoot verifies that an -init file kept in a source root is
+// only prepended as initializer, not bundled a second time.
+func TestServeInitInsideRoot(t *testing.T) {
+	src := writeSources(t, map[string]string{
+		"app.js":       "console.log('app');",
+		"boot/init.js": "console.log('runtime init');",
+	})
+	other := writeSources(t, map[string]string{
+		"boot/init.js": "console.log('other root init');",
+	})
+
+	common := commonFlags{sources: stringList{src, other}, prefix: "/assets/"}
+	am, handler, err := newServer(common.config(nil), filepath.Join(src, "boot", "init.js"), "")
+	require.NoError(t, err)
+
+	summary, err := am.ScanDirectories(src, other)
+	require.NoError(t, err)
+	// only the -init file itself is left out, not the same path in another root
+	require.Equal(t, []string{filepath.Join(src, "app.js"), filepath.Join(other, "boot", "init.js")}, summary["script.js"])
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/assets/script.js", nil))
+	require.Equal(t, 1, strings.Count(rec.Body.String(), "runtime init"))
+	require.Contains(t, rec.Body.String(), "other root init")
+}
+
+func TestBuildBudgets(t *testing.T) {
+	src := writeSources(t, map[string]string{
+		"app.css": ".application-wrapper { color: red; margin: 0 auto; }",
+	})
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"build", "-src", src, "-out", filepath.Join(t.TempDir(), "public"), "-budget", "style.css:minified=10"}, &stdout, &stderr)
+	require.Equal(t, 0, code, stderr.String())
+	require.Contains(t, stderr.String(), "level=WARN")
+	require.Contains(t, stderr.String(), "size budget exceeded")
+
+	stderr.Reset()
+	code = run([]string{"build", "-src", src, "-out", filepath.Join(t.TempDir(), "public"), "-budget", "style.css:minified=10", "-strict"}, &stdout, &stderr)
+	require.Equal(t, 1, code)
+	require.Contains(t, stderr.String(), "style.css minified")
+
+	require.Equal(t, 2, run([]string{"build", "-budget", "style.css=10"}, &stdout, &stderr))
+
+	// the reported gzip size is the one the gzip budget checks
+	out := filepath.Join(t.TempDir(), "public")
+	require.Equal(t, 0, run([]string{"build", "-src", src, "-out", out}, &stdout, &stderr))
+	data, err := os.ReadFile(filepath.Join(out, assetmin.ManifestFileName))
+	require.NoError(t, err)
+	var manifest assetmin.Manifest
+	require.NoError(t, json.Unmarshal(data, &manifest))
+	gz := manifest["style.css"].GzipSize
+	require.NotZero(t, gz)
+
+	stderr.Reset()
+	budget := "style.css:gzip=" + strconv.Itoa(gz)
+	code = run([]string{"build", "-src", src, "-out", filepath.Join(t.TempDir(), "public"), "-budget", budget, "-strict"}, &stdout, &stderr)
+	require.Equal(t, 0, code, stderr.String())
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/tinywasm/assetmin"
+)
+
+// serve starts a development server that keeps the assets in memory and
+// rebuilds them while the source roots change
+func serve(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+
+	var common commonFlags
+	common.register(fs)
+	addr := fs.String("addr", "localhost:8080", "HTTP listen address")
+	initFile := fs.String("init", "", "JavaScript file prepended to script.js as runtime initializer, left out of the bundles when inside a source root")
+	interval := fs.Duration("interval", 500*time.Millisecond, "polling interval of the watcher")
+	snapshotFile := fs.String("snapshot", "", "state file restored at startup and saved on exit for fast warm starts")
+
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	logger := func(message ...any) { fmt.Fprintln(stderr, message...) }
+	config := common.config(logger)
+	config.WatchInterval = *interval
+
+	am, handler, err := newServer(config, *initFile, *snapshotFile)
+	if err != nil {
+		fmt.Fprintln(stderr, "assetmin serve:", err)
+		return 1
+	}
+
+	if err := am.StartWatcher(); err != nil {
+		fmt.Fprintln(stderr, "assetmin serve:", err)
+		return 1
+	}
+	defer am.StopWatcher()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if *initFile != "" {
+		go watchInitFile(ctx, am, *initFile, *interval)
+	}
+
+	srv := &http.Server{Addr: *addr, Handler: handler}
+	errCh := make(chan error, 1)
+	go func() { errCh <- srv.ListenAndServe() }()
+
+	fmt.Fprintf(stdout, "assetmin serving %v on http://%s\n", config.SourceDirs, *addr)
+
+	select {
+	case err := <-errCh:
+		fmt.Fprintln(stderr, "assetmin serve:", err)
+		return 1
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := srv.Shutdown(shutdownCtx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			fmt.Fprintln(stderr, "assetmin serve:", err)
+			return 1
+		}
+		if *snapshotFile != "" {
+			if err := am.SaveSnapshot(*snapshotFile); err != nil {
+				fmt.Fprintln(stderr, "assetmin serve:", err)
+				return 1
+			}
+		}
+		return 0
+	}
+}
+
+// newServer creates an AssetMin in MemoryMode loaded with the sources and the
+// HTTP handler serving its assets. initFile is read on every build of script.js
+// and snapshotFile, when it exists, restores the state of the previous run.
+func newServer(config *assetmin.Config, initFile, snapshotFile string) (*assetmin.AssetMin, http.Handler, error) {
+	if initFile != "" {
+		if _, err := os.Stat(initFile); err != nil {
+			return nil, nil, err
+		}
+		config.GetRuntimeInitializerJS = func() (string, error) {
+			data, err := os.ReadFile(initFile)
+			return string(data), err
+		}
+		// already prepended, a copy kept in a source root isn't bundled again
+		config.IgnoreFiles = append(config.IgnoreFiles, initFile)
+	}
+
+	am := assetmin.NewAssetMin(config)
+	am.SetWorkMode(assetmin.MemoryMode)
+
+	if snapshotFile != "" {
+		if _, err := os.Stat(snapshotFile); err == nil {
+			if _, err := am.LoadSnapshot(snapshotFile); err != nil && config.Logger != nil {
+				config.Logger("assetmin serve:", err)
+			}
+		}
+	}
+
+	if _, err := am.ScanDirectories(config.SourceDirs...); err != nil && config.Logger != nil {
+		// a broken file must not stop the server, it is reported and fixed while watching
+		config.Logger("assetmin serve:", err)
+	}
+
+	mux := http.NewServeMux()
+	am.RegisterRoutes(mux)
+	am.RegisterDebugRoutes(mux)
+	return am, mux, nil
+}
+
+// watchInitFile rebuilds script.js when the runtime initializer file changes,
+// it is not part of the source roots so the watcher doesn't see it
+func watchInitFile(ctx context.Context, am *assetmin.AssetMin, initFile string, interval time.Duration) {
+	var last time.Time
+	if info, err := os.Stat(initFile); err == nil {
+		last = info.ModTime()
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			info, err := os.Stat(initFile)
+			if err != nil || info.ModTime().Equal(last) {
+				continue
+			}
+			last = info.ModTime()
+			am.RefreshAsset(".js")
+		}
+	}
+}
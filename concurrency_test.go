@@ -31,7 +31,7 @@ func TestCacheConcurrency(t *testing.T) {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			_, err := env.AssetsHandler.mainJsHandler.GetMinifiedContent(env.AssetsHandler.min)
+			_, err := env.AssetsHandler.mainJsHandler.GetMinifiedContent(env.AssetsHandler.minifier())
 			require.NoError(t, err)
 		}()
 	}
@@ -48,7 +48,7 @@ func TestCacheConcurrency(t *testing.T) {
 	wg.Wait()
 
 	// Final check of the content
-	finalContent, err := env.AssetsHandler.mainJsHandler.GetMinifiedContent(env.AssetsHandler.min)
+	finalContent, err := env.AssetsHandler.mainJsHandler.GetMinifiedContent(env.AssetsHandler.minifier())
 	require.NoError(t, err)
 	require.Contains(t, string(finalContent), "Updated JS")
 }
@@ -0,0 +1,119 @@
+package assetmin
+
+import (
+	"errors"
+	"mime"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// AssetConfig describes an extra asset registered with RegisterAsset
+type AssetConfig struct {
+	Name      string   // output name eg: print.css, worker.js, data.json
+	MediaType string   // eg: application/json (default: from the extension of Name)
+	URLPath   string   // HTTP route (default: AssetsURLPrefix + Name)
+	Match     []string // gitignore style patterns of the source files it bundles eg: *.print.css, workers/, see RegisterAsset
+	Open      string   // content written before the source files eg: "["
+	Close     string   // content written after the source files eg: "]"
+	Separator string   // content written between two source files eg: "," to make a JSON array with Open "[" and Close "]"
+}
+
+// RegisterAsset adds an asset beyond the built-in ones. Source files matching
+// cfg.Match go to this asset instead of the default one for their extension,
+// only when they are of the same kind: the extension of cfg.Name or one of its
+// media type, so "workers/" in worker.js leaves the css of workers/ in style.css.
+// Patterns are relative to the source root containing the file (Config.SourceDirs,
+// the roots given to ScanDirectories or the root of LoadFS). A file outside every
+// root is matched by its path as given, where anchored patterns eg: "/workers"
+// don't apply. The asset takes part in the HTTP routes,
+// DiskMode writes, the manifest, exports and output path detection. Media types
+// without a minifier are served unminified. Register assets before loading files
+// and before RegisterRoutes.
+func (c *AssetMin) RegisterAsset(cfg AssetConfig) error {
+	const e = "RegisterAsset "
+	if cfg.Name == "" || !filepath.IsLocal(cfg.Name) || filepath.Base(cfg.Name) != cfg.Name {
+		return errors.New(e + "invalid name " + cfg.Name)
+	}
+	if len(cfg.Match) == 0 {
+		return errors.New(e + cfg.Name + " has no Match patterns")
+	}
+
+	mediaType := cfg.MediaType
+	if mediaType == "" {
+		mediaType = mime.TypeByExtension(path.Ext(cfg.Name))
+	}
+	mediaType, _, err := mime.ParseMediaType(mediaType)
+	if err != nil {
+		return errors.New(e + cfg.Name + " unknown media type, set MediaType")
+	}
+
+	urlPath := cfg.URLPath
+	if urlPath == "" {
+		urlPath = path.Join("/", c.AssetsURLPrefix, cfg.Name)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, fh := range c.assets() {
+		if fh.fileOutputName == cfg.Name || fh.urlPath == urlPath {
+			return errors.New(e + cfg.Name + " conflicts with " + fh.fileOutputName)
+		}
+	}
+
+	fh := newAssetFile(cfg.Name, mediaType, c.Config, nil)
+	fh.urlPath = urlPath
+	fh.match = parseIgnoreRules(cfg.Match)
+	fh.separator = cfg.Separator
+	if cfg.Open != "" {
+		fh.contentOpen = append(fh.contentOpen, &contentFile{path: cfg.Name + "-open", content: []byte(cfg.Open)})
+	}
+	if cfg.Close != "" {
+		fh.contentClose = append(fh.contentClose, &contentFile{path: cfg.Name + "-close", content: []byte(cfg.Close)})
+	}
+
+	c.customAssets = append(c.customAssets, fh)
+	return nil
+}
+
+// customAssetFor returns the first custom asset of the kind of filePath whose
+// patterns match it
+func (c *AssetMin) customAssetFor(filePath string) *asset {
+	if len(c.customAssets) == 0 {
+		return nil
+	}
+	rel := c.ignore.relativePath(filePath)
+	for _, fh := range c.customAssets {
+		if fh.accepts(filePath) && fh.match.ignored(rel, false) {
+			return fh
+		}
+	}
+	return nil
+}
+
+// accepts reports whether a source file has the extension of the output or
+// one of its media type eg: .mjs for worker.js
+func (h *asset) accepts(filePath string) bool {
+	ext := path.Ext(filepath.ToSlash(filePath))
+	if ext == "" {
+		return false
+	}
+	if strings.EqualFold(ext, path.Ext(h.fileOutputName)) {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(mime.TypeByExtension(ext))
+	return err == nil && mediaType == h.mediatype
+}
+
+// customExtensions returns the extensions of the custom assets not handled by default
+func (c *AssetMin) customExtensions() []string {
+	var out []string
+	for _, fh := range c.customAssets {
+		if ext := path.Ext(fh.fileOutputName); ext != "" && !slices.Contains(out, ext) {
+			out = append(out, ext)
+		}
+	}
+	return out
+}
@@ -0,0 +1,134 @@
+package assetmin
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestRegisterAsset verifies that custom assets take their matching files and
+// join routing, DiskMode writes, the manifest and output path detection.
+func TestRegisterAsset(t *testing.T) {
+	env := setupTestEnv("register_asset", t)
+	env.AssetsHandler.SetWorkMode(DiskMode)
+	defer env.CleanDirectory()
+	am := env.AssetsHandler
+
+	require.NoError(t, am.RegisterAsset(AssetConfig{Name: "print.css", Match: []string{"*.print.css"}}))
+	require.NoError(t, am.RegisterAsset(AssetConfig{Name: "worker.js", Match: []string{"workers/"}, Open: "(function(){", Close: "})();"}))
+	require.NoError(t, am.RegisterAsset(AssetConfig{Name: "data.json", Match: []string{"data/*.json"}, Open: "[", Close: "]", Separator: ","}))
+
+	files := map[string]string{
+		filepath.Join(env.ModulesDir, "app", "app.css"):       ".app { color: red; }",
+		filepath.Join(env.ModulesDir, "app", "app.print.css"): ".app { color: black; }",
+		filepath.Join(env.ModulesDir, "app", "app.js"):        "console.log('main');",
+		filepath.Join(env.ModulesDir, "workers", "sync.js"):   "self.onmessage = function () {};",
+		filepath.Join(env.ModulesDir, "data", "items.json"):   "{ \"items\": [1, 2] }",
+		filepath.Join(env.ModulesDir, "data", "more.json"):    "{ \"more\": true }",
+		filepath.Join(env.ModulesDir, "other", "skip.json"):   "{}",
+	}
+	for path, content := range files {
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+
+	summary, err := am.ScanDirectories(env.ModulesDir)
+	require.NoError(t, err)
+	require.Equal(t, []string{filepath.Join(env.ModulesDir, "app", "app.print.css")}, summary["print.css"])
+	require.Equal(t, []string{filepath.Join(env.ModulesDir, "workers", "sync.js")}, summary["worker.js"])
+	require.Equal(t, []string{filepath.Join(env.ModulesDir, "data", "items.json"), filepath.Join(env.ModulesDir, "data", "more.json")}, summary["data.json"])
+	require.Contains(t, am.SupportedExtensions(), ".json")
+
+	read := func(name string) string {
+		data, err := os.ReadFile(filepath.Join(env.PublicDir, name))
+		require.NoError(t, err)
+		return string(data)
+	}
+	require.Equal(t, ".app{color:red}", read("style.css"))
+	require.Equal(t, ".app{color:#000}", read("print.css"))
+	require.NotContains(t, read("script.js"), "onmessage")
+	require.Contains(t, read("worker.js"), "(function(){self.onmessage=function(){}})()")
+	// no minifier for JSON, the files are passed through joined by the separator
+	data := read("data.json")
+	require.Equal(t, "[\n{ \"items\": [1, 2] }\n,{ \"more\": true }\n]\n", data)
+	require.True(t, json.Valid([]byte(data)))
+
+	manifest, err := am.Manifest()
+	require.NoError(t, err)
+	require.Equal(t, "/worker.js", manifest["worker.js"].URL)
+	require.Equal(t, "application/json", manifest["data.json"].MediaType)
+
+	require.True(t, am.isOutputPath(filepath.Join(env.PublicDir, "print.css")))
+	require.Contains(t, am.UnobservedFiles(), filepath.Join(env.PublicDir, "worker.js"))
+
+	mux := http.NewServeMux()
+	am.RegisterRoutes(mux)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", "/print.css", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "text/css", rec.Header().Get("Content-Type"))
+	body, _ := io.ReadAll(rec.Body)
+	require.Equal(t, ".app{color:#000}", string(body))
+
+	t.Run("unmatched_custom_extension_is_skipped", func(t *testing.T) {
+		pkg := filepath.Join(env.ModulesDir, "package.json")
+		require.NoError(t, os.WriteFile(pkg, []byte(`{"name":"app"}`), 0644))
+		require.NoError(t, am.NewFileEvent("package.json", ".json", pkg, "create"))
+		require.NoError(t, am.NewFileEvent("package.json", ".json", pkg, "remove"))
+		require.Equal(t, data, read("data.json"))
+
+		// an extension nothing supports is still an error
+		notes := filepath.Join(env.ModulesDir, "notes.txt")
+		require.NoError(t, os.WriteFile(notes, []byte("notes"), 0644))
+		err := am.NewFileEvent("notes.txt", ".txt", notes, "create")
+		require.ErrorContains(t, err, "not found")
+	})
+
+	t.Run("invalid_registrations", func(t *testing.T) {
+		require.Error(t, am.RegisterAsset(AssetConfig{Name: "script.js", Match: []string{"*.js"}}))
+		require.Error(t, am.RegisterAsset(AssetConfig{Name: "../up.css", Match: []string{"*.css"}}))
+		require.Error(t, am.RegisterAsset(AssetConfig{Name: "empty.css"}))
+		require.Error(t, am.RegisterAsset(AssetConfig{Name: "blob.unknownext", Match: []string{"*.unknownext"}}))
+	})
+}
+
+// TestRegisterAssetMixedDirectory verifies that a directory pattern only takes
+// the files of the kind of the custom asset, the others stay in their bundles.
+func TestRegisterAssetMixedDirectory(t *testing.T) {
+	env := setupTestEnv("register_asset_mixed", t)
+	defer env.CleanDirectory()
+	am := env.AssetsHandler
+
+	require.NoError(t, am.RegisterAsset(AssetConfig{Name: "worker.js", Match: []string{"workers/"}}))
+
+	dir := filepath.Join(env.ModulesDir, "workers")
+	files := map[string]string{
+		filepath.Join(dir, "sync.js"):    "self.onmessage = function () {};",
+		filepath.Join(dir, "helper.mjs"): "self.helper = 1;",
+		filepath.Join(dir, "panel.css"):  ".panel { color: red; }",
+		filepath.Join(dir, "gear.svg"):   `<symbol id="gear"><path d="M0 0"/></symbol>`,
+		filepath.Join(dir, "panel.html"): "<div>panel</div>",
+	}
+	for path, content := range files {
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+
+	summary, err := am.ScanDirectories(env.ModulesDir)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{filepath.Join(dir, "sync.js"), filepath.Join(dir, "helper.mjs")}, summary["worker.js"])
+	require.Equal(t, []string{filepath.Join(dir, "panel.css")}, summary["style.css"])
+	require.Equal(t, []string{filepath.Join(dir, "gear.svg")}, summary["sprite.svg"])
+	require.Equal(t, []string{filepath.Join(dir, "panel.html")}, summary["index.html"])
+
+	worker, err := am.customAssets[0].GetMinifiedContent(am.minifier())
+	require.NoError(t, err)
+	require.NotContains(t, string(worker), "panel")
+	require.NotContains(t, string(worker), "gear")
+}
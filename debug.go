@@ -0,0 +1,57 @@
+package assetmin
+
+import (
+	"encoding/json"
+	"net/http"
+	"path"
+)
+
+// DebugURLPrefix is the route prefix of the development endpoints
+const DebugURLPrefix = "/_assetmin/"
+
+// RegisterDebugRoutes registers the development endpoints under DebugURLPrefix:
+//   - analysis.json: the composition of every bundle
+//   - analysis.html: the same composition as a page
+//   - diagnostics.json: the build errors located in their source files
+//   - conflicts.json: the duplicates found across source files
+//
+// They expose source paths, register them only in development.
+func (c *AssetMin) RegisterDebugRoutes(mux *http.ServeMux) {
+	mux.HandleFunc(path.Join(DebugURLPrefix, "analysis.json"), c.serveAnalysisJSON)
+	mux.HandleFunc(path.Join(DebugURLPrefix, "analysis.html"), c.serveAnalysisHTML)
+	mux.HandleFunc(path.Join(DebugURLPrefix, "diagnostics.json"), c.serveDiagnosticsJSON)
+	mux.HandleFunc(path.Join(DebugURLPrefix, "conflicts.json"), c.serveConflictsJSON)
+}
+
+func (c *AssetMin) serveAnalysisJSON(w http.ResponseWriter, r *http.Request) {
+	// assets that fail to build are left out, the others are still useful
+	report, _ := c.Analyze()
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(report)
+}
+
+func (c *AssetMin) serveAnalysisHTML(w http.ResponseWriter, r *http.Request) {
+	report, _ := c.Analyze()
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
+	_ = report.WriteHTML(w)
+}
+
+func (c *AssetMin) serveDiagnosticsJSON(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(c.Diagnostics())
+}
+
+func (c *AssetMin) serveConflictsJSON(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(c.Conflicts())
+}
@@ -0,0 +1,153 @@
+package assetmin
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/tdewolff/parse/v2"
+)
+
+// Diagnostic is a minify or parse error located in the source file that caused
+// it instead of the concatenated bundle
+type Diagnostic struct {
+	Asset   string `json:"asset"`   // output name eg: script.js
+	File    string `json:"file"`    // source file, empty when the position is outside every file
+	Line    int    `json:"line"`    // 1-based line in File, in the bundle when File is empty
+	Column  int    `json:"column"`  // 1-based column in runes
+	Message string `json:"message"` // eg: unexpected ; in expression
+	Excerpt string `json:"excerpt"` // the source line with a caret under Column
+
+	Quarantined bool `json:"quarantined"` // the file failed validation and is left out of the bundle
+}
+
+// Error formats the diagnostic as "file:line:column: message" followed by the excerpt
+func (d *Diagnostic) Error() string {
+	where := d.File
+	if where == "" {
+		where = d.Asset
+	}
+	out := where + ":" + strconv.Itoa(d.Line) + ":" + strconv.Itoa(d.Column) + ": " + d.Message
+	if d.Excerpt != "" {
+		out += "\n" + d.Excerpt
+	}
+	return out
+}
+
+// Diagnostics returns the quarantined source files and the errors of the last
+// build of every asset, empty when everything built. Use errors.As on the error
+// of an event to get a single one.
+func (c *AssetMin) Diagnostics() []Diagnostic {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	out := []Diagnostic{}
+	for _, fh := range c.assets() {
+		for _, f := range fh.contentMiddle {
+			if f.invalid != nil {
+				out = append(out, *f.invalid)
+			}
+		}
+		fh.mu.RLock()
+		if fh.diagnostic != nil {
+			out = append(out, *fh.diagnostic)
+		}
+		fh.mu.RUnlock()
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Asset < out[j].Asset })
+	return out
+}
+
+// segment is the place of one source file in the bundle, used to map the
+// position of a minify error back to the file
+type segment struct {
+	path    string
+	line    int    // 1-based line where the file starts in the bundle
+	column  int    // 1-based column where the file starts, init code has no trailing newline
+	content []byte // the content as written, after TransformFile
+}
+
+// segmentWriter tracks the position of every file written to the bundle
+type segmentWriter struct {
+	segments     []segment
+	line, column int
+}
+
+func newSegmentWriter() *segmentWriter {
+	return &segmentWriter{line: 1, column: 1}
+}
+
+// add records content at the current position and advances past it
+func (s *segmentWriter) add(path string, content []byte) {
+	s.segments = append(s.segments, segment{path: path, line: s.line, column: s.column, content: content})
+	s.advance(content)
+}
+
+// advance moves the position past b counting lines like the tdewolff parsers:
+// \n, \r\n, a lone \r, U+2028 and U+2029
+func (s *segmentWriter) advance(b []byte) {
+	for i := 0; i < len(b); {
+		r, n := utf8.DecodeRune(b[i:])
+		switch {
+		case r == '\r' && i+1 < len(b) && b[i+1] == '\n':
+			n = 2
+			fallthrough
+		case r == '\n', r == '\r', r == '\u2028', r == '\u2029':
+			s.line, s.column = s.line+1, 1
+		default:
+			s.column++
+		}
+		i += n
+	}
+}
+
+// diagnose maps a parse error of the bundle to the source file in segments.
+// Other errors are returned unchanged.
+func (h *asset) diagnose(err error, segments []segment) error {
+	var perr *parse.Error
+	if !errors.As(err, &perr) {
+		return err
+	}
+
+	d := &Diagnostic{Asset: h.fileOutputName, Line: perr.Line, Column: perr.Column, Message: perr.Message}
+	for i := len(segments) - 1; i >= 0; i-- {
+		s := segments[i]
+		if perr.Line < s.line || (perr.Line == s.line && perr.Column < s.column) {
+			continue
+		}
+		d.File = s.path
+		d.Line = perr.Line - s.line + 1
+		if perr.Line == s.line {
+			d.Column = perr.Column - s.column + 1
+		}
+		d.Excerpt = excerpt(s.content, d.Line, d.Column)
+		break
+	}
+	return d
+}
+
+// excerpt returns line of content with a caret under column, empty when the
+// line doesn't exist eg: an unexpected end of file
+func excerpt(content []byte, line, column int) string {
+	lines := strings.Split(strings.ReplaceAll(string(content), "\r\n", "\n"), "\n")
+	if line < 1 || line > len(lines) {
+		return ""
+	}
+	text := lines[line-1]
+
+	// tabs are kept so the caret lines up with the source
+	var pad strings.Builder
+	for i, r := range []rune(text) {
+		if i >= column-1 {
+			break
+		}
+		if r == '\t' {
+			pad.WriteRune('\t')
+		} else {
+			pad.WriteByte(' ')
+		}
+	}
+	return text + "\n" + pad.String() + "^"
+}
@@ -0,0 +1,107 @@
+package assetmin
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tdewolff/parse/v2"
+)
+
+// TestDiagnostics verifies that a minify error is reported in the source file
+// that caused it, with the line and column of that file.
+func TestDiagnostics(t *testing.T) {
+	env := setupTestEnv("diagnostics", t)
+	defer env.CleanDirectory()
+	am := env.AssetsHandler
+
+	write := func(name, content string) string {
+		path := filepath.Join(env.ModulesDir, "diag", name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+		return path
+	}
+
+	first := write("a.js", "console.log('a');\nlet x = 1;\n")
+	require.NoError(t, am.NewFileEventKind("a.js", ".js", first, EventCreate))
+
+	// valid on its own, the bundle declares x twice
+	broken := write("b.js", "let ok = 1;\n\tlet x = 2;\n")
+	err := am.NewFileEventKind("b.js", ".js", broken, EventCreate)
+	require.Error(t, err)
+
+	var diag *Diagnostic
+	require.True(t, errors.As(err, &diag), err.Error())
+	require.Equal(t, "script.js", diag.Asset)
+	require.Equal(t, broken, diag.File)
+	require.Equal(t, 2, diag.Line)
+	require.Equal(t, 6, diag.Column)
+	require.Equal(t, "\tlet x = 2;\n\t    ^", diag.Excerpt)
+	require.False(t, diag.Quarantined)
+	require.Contains(t, err.Error(), broken+":2:6: identifier x has already been declared")
+
+	require.Equal(t, []Diagnostic{*diag}, am.Diagnostics())
+
+	t.Run("debug_route", func(t *testing.T) {
+		mux := http.NewServeMux()
+		am.RegisterDebugRoutes(mux)
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest("GET", DebugURLPrefix+"diagnostics.json", nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		var got []Diagnostic
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+		require.Len(t, got, 1)
+		require.Equal(t, broken, got[0].File)
+	})
+
+	t.Run("fixed", func(t *testing.T) {
+		write("b.js", "let ok = 1;\nlet y = 2;\n")
+		require.NoError(t, am.NewFileEventKind("b.js", ".js", broken, EventWrite))
+		require.Empty(t, am.Diagnostics())
+	})
+}
+
+// TestSegmentPositions verifies the mapping when a file starts in the middle of
+// a line, as the first one after the init code does.
+func TestSegmentPositions(t *testing.T) {
+	pos := newSegmentWriter()
+	pos.add("(init code)", []byte("'use strict';"))
+	pos.add("a.js", []byte("let a;\r\nlet b;\n"))
+	pos.add("b.js", []byte("x"))
+
+	h := &asset{fileOutputName: "script.js"}
+	require.Equal(t, segment{path: "a.js", line: 1, column: 14, content: []byte("let a;\r\nlet b;\n")}, pos.segments[1])
+	require.Equal(t, 3, pos.segments[2].line)
+
+	for _, tc := range []struct {
+		line, column int
+		file         string
+		wantLine     int
+		wantColumn   int
+	}{
+		{1, 5, "(init code)", 1, 5},
+		{1, 18, "a.js", 1, 5},
+		{2, 3, "a.js", 2, 3},
+		{3, 1, "b.js", 1, 1},
+	} {
+		err := h.diagnose(parseError(tc.line, tc.column), pos.segments)
+		var diag *Diagnostic
+		require.True(t, errors.As(err, &diag))
+		require.Equal(t, tc.file, diag.File)
+		require.Equal(t, tc.wantLine, diag.Line)
+		require.Equal(t, tc.wantColumn, diag.Column)
+	}
+
+	other := errors.New("not a parse error")
+	require.Equal(t, other, h.diagnose(other, pos.segments))
+}
+
+func parseError(line, column int) error {
+	return &parse.Error{Message: "unexpected", Line: line, Column: column}
+}
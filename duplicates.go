@@ -0,0 +1,312 @@
+package assetmin
+
+import (
+	"bytes"
+	"sort"
+	"strings"
+
+	"github.com/tdewolff/parse/v2"
+	"github.com/tdewolff/parse/v2/css"
+	"github.com/tdewolff/parse/v2/js"
+	"github.com/tdewolff/parse/v2/xml"
+)
+
+// ConflictKind identifies what two or more source files of an asset duplicate
+type ConflictKind string
+
+const (
+	ConflictIdenticalFile ConflictKind = "identical-file" // byte-identical files
+	ConflictSVGID         ConflictKind = "svg-id"         // an id attribute declared by more than one icon
+	ConflictCSSSelector   ConflictKind = "css-selector"   // a selector setting a property to different values
+	ConflictJSDeclaration ConflictKind = "js-declaration" // a top-level var, let, const, function or class
+)
+
+// Conflict is a duplicate found across the source files of an asset. They are
+// warnings: the asset still builds, except for let, const and class which the
+// minifier rejects when declared twice.
+type Conflict struct {
+	Kind    ConflictKind `json:"kind"`
+	Asset   string       `json:"asset"`   // output name eg: style.css
+	Name    string       `json:"name"`    // the selector, id or identifier, empty for identical files
+	Files   []string     `json:"files"`   // the files involved in bundle order
+	Message string       `json:"message"` // eg: .btn sets color to red in a.css, blue in b.css
+}
+
+// key identifies a conflict to log it once while it lasts
+func (c Conflict) key() string {
+	return string(c.Kind) + "\x00" + c.Name + "\x00" + c.Message
+}
+
+// Conflicts returns the duplicates found across the source files of every
+// asset, they are also logged as warnings when they first appear
+func (c *AssetMin) Conflicts() []Conflict {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	out := []Conflict{}
+	for _, fh := range c.assets() {
+		out = append(out, fh.findConflicts()...)
+	}
+	return out
+}
+
+// reportConflicts logs the conflicts of fh that weren't found by the previous
+// build. The caller must hold c.mu.
+func (c *AssetMin) reportConflicts(fh *asset) {
+	found := fh.findConflicts()
+	seen := make(map[string]bool, len(found))
+	for _, conflict := range found {
+		seen[conflict.key()] = true
+		if fh.conflicts[conflict.key()] {
+			continue
+		}
+		c.log().Warn("duplicate content", LogKeyAsset, fh.fileOutputName, LogKeyConflict, string(conflict.Kind),
+			LogKeyFiles, conflict.Files, LogKeyDetail, conflict.Message)
+	}
+	fh.conflicts = seen
+}
+
+// fileFacts is what a source file declares, computed once per content
+type fileFacts struct {
+	ids   []string             // svg id attributes
+	names []string             // js top-level declarations
+	rules map[[2]string]string // css selector and property to value
+}
+
+// findConflicts compares the facts of the source files of fh. Only contentMiddle
+// is scanned: contentOpen and contentClose are the wrappers the asset adds
+// itself eg: the svg root or the html head, not source files that could
+// duplicate each other. The caller must hold c.mu.
+func (h *asset) findConflicts() []Conflict {
+	var out []Conflict
+	byHash := map[string][]string{}
+	var hashes []string
+	ids := map[string][]string{}
+	names := map[string][]string{}
+	rules := map[[2]string][]string{} // values in file order, parallel to ruleFiles
+	ruleFiles := map[[2]string][]string{}
+
+	for _, f := range h.contentMiddle {
+		if f.invalid != nil || len(bytes.TrimSpace(f.content)) == 0 {
+			continue
+		}
+		hash := f.hash()
+		_, copied := byHash[hash]
+		byHash[hash] = append(byHash[hash], f.path)
+		if copied {
+			continue // reported as an identical file, not once per declaration
+		}
+		hashes = append(hashes, hash)
+
+		facts := h.factsOf(f)
+		for _, id := range facts.ids {
+			ids[id] = appendUnique(ids[id], f.path)
+		}
+		for _, name := range facts.names {
+			names[name] = appendUnique(names[name], f.path)
+		}
+		for key, value := range facts.rules {
+			rules[key] = append(rules[key], value)
+			ruleFiles[key] = append(ruleFiles[key], f.path)
+		}
+	}
+
+	for _, hash := range hashes {
+		if files := byHash[hash]; len(files) > 1 {
+			out = append(out, Conflict{Kind: ConflictIdenticalFile, Asset: h.fileOutputName, Files: files,
+				Message: "identical content in " + strings.Join(files, ", ")})
+		}
+	}
+	for id, files := range ids {
+		if len(files) > 1 {
+			out = append(out, Conflict{Kind: ConflictSVGID, Asset: h.fileOutputName, Name: id, Files: files,
+				Message: "id " + id + " declared in " + strings.Join(files, ", ")})
+		}
+	}
+	for name, files := range names {
+		if len(files) > 1 {
+			out = append(out, Conflict{Kind: ConflictJSDeclaration, Asset: h.fileOutputName, Name: name, Files: files,
+				Message: name + " declared at top level in " + strings.Join(files, ", ")})
+		}
+	}
+	for key, values := range rules {
+		if len(values) < 2 || allEqual(values) {
+			continue
+		}
+		files := ruleFiles[key]
+		var parts []string
+		for i, v := range values {
+			parts = append(parts, v+" in "+files[i])
+		}
+		out = append(out, Conflict{Kind: ConflictCSSSelector, Asset: h.fileOutputName, Name: key[0], Files: files,
+			Message: key[0] + " sets " + key[1] + " to " + strings.Join(parts, ", ")})
+	}
+
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].Kind != out[j].Kind {
+			return out[i].Kind < out[j].Kind
+		}
+		return out[i].Message < out[j].Message
+	})
+	return out
+}
+
+// factsOf returns the facts of f, parsing its transformed content on the first call
+func (h *asset) factsOf(f *contentFile) *fileFacts {
+	if f.facts != nil {
+		return f.facts
+	}
+	facts := &fileFacts{}
+	content, err := h.transformFile(f)
+	if err == nil {
+		switch {
+		case h.mediatype == "image/svg+xml":
+			facts.ids = svgIDs(content)
+		case h.mediatype == "text/css":
+			facts.rules = cssRules(content)
+		case jsMediaType.MatchString(h.mediatype):
+			facts.names = jsDeclarations(content)
+		}
+	}
+	f.facts = facts
+	return facts
+}
+
+// svgIDs returns the id attributes of an svg file
+func svgIDs(content []byte) []string {
+	var ids []string
+	l := xml.NewLexer(parse.NewInputBytes(content))
+	for {
+		tt, _ := l.Next()
+		if tt == xml.ErrorToken {
+			return ids
+		}
+		if tt == xml.AttributeToken && string(l.Text()) == "id" {
+			if id := strings.Trim(string(l.AttrVal()), `"'`); id != "" {
+				ids = appendUnique(ids, id)
+			}
+		}
+	}
+}
+
+// jsDeclarations returns the names declared at the top level of a JS file,
+// var declared in nested blocks included since they are hoisted
+func jsDeclarations(content []byte) []string {
+	ast, err := js.Parse(parse.NewInputBytes(content), js.Options{})
+	if err != nil {
+		return nil
+	}
+	var names []string
+	for _, v := range ast.Scope.Declared {
+		switch v.Decl {
+		case js.VariableDecl, js.FunctionDecl, js.LexicalDecl:
+			names = appendUnique(names, string(v.Data))
+		}
+	}
+	return names
+}
+
+// cssRules returns the value each selector sets to each property, the last one
+// of the file wins. Selectors inside at-rules or nested rules are prefixed with
+// their context eg: "@media (max-width:600px) .btn".
+func cssRules(content []byte) map[[2]string]string {
+	rules := map[[2]string]string{}
+	var context []cssBlock
+	p := css.NewParser(parse.NewInputBytes(content), false)
+	for {
+		gt, _, data := p.Next()
+		switch gt {
+		case css.ErrorGrammar:
+			return rules
+		case css.BeginAtRuleGrammar:
+			prelude := strings.TrimSpace(string(data) + " " + tokensText(p.Values()))
+			context = append(context, cssBlock{selectors: []string{prelude}, atRule: true})
+		case css.BeginRulesetGrammar:
+			prefix := contextPrefix(context)
+			var selectors []string
+			for _, s := range splitTokens(p.Values()) {
+				selectors = append(selectors, prefix+s)
+			}
+			context = append(context, cssBlock{selectors: selectors})
+		case css.EndAtRuleGrammar, css.EndRulesetGrammar:
+			if len(context) > 0 {
+				context = context[:len(context)-1]
+			}
+		case css.DeclarationGrammar, css.CustomPropertyGrammar:
+			// declarations of at-rules eg: @font-face are expected to repeat
+			if len(context) == 0 || context[len(context)-1].atRule {
+				continue
+			}
+			property := strings.ToLower(string(data))
+			value := tokensText(p.Values())
+			for _, s := range context[len(context)-1].selectors {
+				rules[[2]string{s, property}] = value
+			}
+		}
+	}
+}
+
+// cssBlock is an open ruleset or at-rule while reading a CSS file
+type cssBlock struct {
+	selectors []string // the prelude for an at-rule
+	atRule    bool
+}
+
+// contextPrefix joins the at-rules and parent selectors of the open blocks
+func contextPrefix(context []cssBlock) string {
+	var prefix string
+	for _, b := range context {
+		prefix += strings.Join(b.selectors, ",") + " "
+	}
+	return prefix
+}
+
+// splitTokens splits a selector list on its top-level commas
+func splitTokens(tokens []css.Token) []string {
+	var out []string
+	var cur []css.Token
+	depth := 0
+	for _, t := range tokens {
+		switch t.TokenType {
+		case css.LeftParenthesisToken, css.FunctionToken, css.LeftBracketToken:
+			depth++
+		case css.RightParenthesisToken, css.RightBracketToken:
+			depth--
+		case css.CommaToken:
+			if depth == 0 {
+				out = append(out, tokensText(cur))
+				cur = nil
+				continue
+			}
+		}
+		cur = append(cur, t)
+	}
+	return append(out, tokensText(cur))
+}
+
+// tokensText joins tokens collapsing whitespace
+func tokensText(tokens []css.Token) string {
+	var b strings.Builder
+	for _, t := range tokens {
+		b.Write(t.Data)
+	}
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+func appendUnique(list []string, s string) []string {
+	for _, v := range list {
+		if v == s {
+			return list
+		}
+	}
+	return append(list, s)
+}
+
+func allEqual(values []string) bool {
+	for _, v := range values[1:] {
+		if v != values[0] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,102 @@
+package assetmin
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestConflicts verifies the duplicates reported across the source files of
+// each asset and that every one of them is logged once.
+func TestConflicts(t *testing.T) {
+	env := setupTestEnv("conflicts", t)
+	defer env.CleanDirectory()
+	am := env.AssetsHandler
+
+	var logs []string
+	am.Logger = func(message ...any) {
+		logs = append(logs, fmt.Sprintln(message...))
+	}
+
+	files := map[string]string{}
+	create := func(module, name, content string) string {
+		path := filepath.Join(env.ModulesDir, module, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+		require.NoError(t, am.NewFileEventKind(name, filepath.Ext(name), path, EventCreate))
+		files[module+"/"+name] = path
+		return path
+	}
+
+	create("a", "button.css", ".btn{color:red;padding:4px}\n@media (max-width:600px){.btn{color:red}}\n@font-face{font-family:x;src:url(a.woff)}")
+	create("b", "button.css", ".btn{color:blue;padding:4px}\n@media (max-width:600px){.btn{color:red}}\n@font-face{font-family:y;src:url(b.woff)}")
+	create("c", "copy.css", ".copy{margin:0}")
+	create("d", "copy.css", ".copy{margin:0}")
+
+	create("a", "a.js", "var config = 1;\nfunction init() {}\nif (true) { var hoisted = 1; }\nfunction local() { var inner = 1; }")
+	create("b", "b.js", "var config = 2;\nfunction init() { return 2; }\nvar hoisted = 2;\nfunction other() { var inner = 2; }")
+
+	create("a", "home.svg", `<symbol id="icon-home"><path d="M0 0"/></symbol>`)
+	create("b", "house.svg", `<symbol id="icon-home"><path d="M1 1"/></symbol>`)
+
+	conflicts := am.Conflicts()
+	byKind := map[ConflictKind][]Conflict{}
+	for _, c := range conflicts {
+		byKind[c.Kind] = append(byKind[c.Kind], c)
+	}
+
+	require.Len(t, byKind[ConflictCSSSelector], 1, "same values and at-rules aren't conflicts")
+	css := byKind[ConflictCSSSelector][0]
+	require.Equal(t, "style.css", css.Asset)
+	require.Equal(t, ".btn", css.Name)
+	require.Equal(t, []string{files["a/button.css"], files["b/button.css"]}, css.Files)
+	require.Equal(t, ".btn sets color to red in "+files["a/button.css"]+", blue in "+files["b/button.css"], css.Message)
+
+	require.Len(t, byKind[ConflictIdenticalFile], 1)
+	require.Equal(t, []string{files["c/copy.css"], files["d/copy.css"]}, byKind[ConflictIdenticalFile][0].Files)
+
+	var names []string
+	for _, c := range byKind[ConflictJSDeclaration] {
+		require.Equal(t, "script.js", c.Asset)
+		names = append(names, c.Name)
+	}
+	require.ElementsMatch(t, []string{"config", "init", "hoisted"}, names)
+
+	require.Len(t, byKind[ConflictSVGID], 1)
+	require.Equal(t, "icon-home", byKind[ConflictSVGID][0].Name)
+	require.Equal(t, "sprite.svg", byKind[ConflictSVGID][0].Asset)
+
+	warnings := 0
+	for _, line := range logs {
+		if strings.Contains(line, "warn: duplicate content") {
+			warnings++
+		}
+	}
+	require.Equal(t, len(conflicts), warnings, "each conflict is logged once")
+
+	t.Run("debug_route", func(t *testing.T) {
+		mux := http.NewServeMux()
+		am.RegisterDebugRoutes(mux)
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest("GET", DebugURLPrefix+"conflicts.json", nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		var got []Conflict
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+		require.Equal(t, conflicts, got)
+	})
+
+	t.Run("resolved", func(t *testing.T) {
+		require.NoError(t, am.NewFileEventKind("copy.css", ".css", files["d/copy.css"], EventRemove))
+		for _, c := range am.Conflicts() {
+			require.NotEqual(t, ConflictIdenticalFile, c.Kind)
+		}
+	})
+}
@@ -0,0 +1,51 @@
+package assetmin
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// EventKind is the kind of change reported for a source file
+type EventKind int
+
+const (
+	EventCreate EventKind = iota + 1 // new file
+	EventWrite                       // existing file modified
+	EventRemove                      // file deleted
+	EventRename                      // file renamed, use RenameFile to pass the new path
+)
+
+// ErrUnknownEvent is returned for event names that ParseEventKind doesn't know
+var ErrUnknownEvent = errors.New("unknown event kind")
+
+var eventNames = map[EventKind]string{
+	EventCreate: "create",
+	EventWrite:  "write",
+	EventRemove: "remove",
+	EventRename: "rename",
+}
+
+func (k EventKind) String() string {
+	if name, ok := eventNames[k]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+// ParseEventKind maps the names emitted by file watchers to an EventKind, case
+// insensitive: create, write, modify, remove, delete and rename. Anything else
+// returns an error wrapping ErrUnknownEvent.
+func ParseEventKind(event string) (EventKind, error) {
+	switch strings.ToLower(strings.TrimSpace(event)) {
+	case "create":
+		return EventCreate, nil
+	case "write", "modify":
+		return EventWrite, nil
+	case "remove", "delete":
+		return EventRemove, nil
+	case "rename":
+		return EventRename, nil
+	}
+	return 0, fmt.Errorf("ParseEventKind %w %q, expected: create, write, modify, remove, delete or rename", ErrUnknownEvent, event)
+}
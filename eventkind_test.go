@@ -0,0 +1,58 @@
+package assetmin
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseEventKind(t *testing.T) {
+	cases := map[string]EventKind{
+		"create": EventCreate,
+		"CREATE": EventCreate,
+		"write":  EventWrite,
+		"modify": EventWrite,
+		"remove": EventRemove,
+		"delete": EventRemove,
+		"rename": EventRename,
+	}
+	for name, want := range cases {
+		got, err := ParseEventKind(name)
+		require.NoError(t, err, name)
+		require.Equal(t, want, got, name)
+	}
+
+	_, err := ParseEventKind("created")
+	require.ErrorIs(t, err, ErrUnknownEvent)
+	require.Contains(t, err.Error(), `"created"`)
+	require.Equal(t, "write", EventWrite.String())
+}
+
+// TestNewFileEventKind verifies the typed API and that a typo in the string API
+// is reported instead of being silently ignored.
+func TestNewFileEventKind(t *testing.T) {
+	env := setupTestEnv("new_file_event_kind", t)
+	env.AssetsHandler.SetWorkMode(DiskMode)
+	defer env.CleanDirectory()
+
+	path := filepath.Join(env.ModulesDir, "kind", "app.js")
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, os.WriteFile(path, []byte("console.log('typed');"), 0644))
+
+	err := env.AssetsHandler.NewFileEvent("app.js", ".js", path, "created")
+	require.True(t, errors.Is(err, ErrUnknownEvent))
+	require.Empty(t, env.AssetsHandler.mainJsHandler.contentMiddle)
+
+	require.NoError(t, env.AssetsHandler.NewFileEventKind("app.js", ".js", path, EventCreate))
+	out, err := os.ReadFile(env.MainJsPath)
+	require.NoError(t, err)
+	require.Contains(t, string(out), "typed")
+
+	require.NoError(t, env.AssetsHandler.NewFileEventKind("app.js", ".js", path, EventRemove))
+	require.Empty(t, env.AssetsHandler.mainJsHandler.contentMiddle)
+
+	require.Error(t, env.AssetsHandler.NewFileEventKind("app.js", ".js", path, EventKind(42)))
+}
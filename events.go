@@ -1,67 +1,162 @@
 package assetmin
 
 import (
-	"bytes"
 	"errors"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"time"
 )
 
+// UpdateFileContentInMemory stores the content of a source file in its asset
+// without rebuilding it. event is parsed with ParseEventKind.
 func (c *AssetMin) UpdateFileContentInMemory(filePath, extension, event string, content []byte) (*asset, error) {
-	file := &contentFile{
-		path:    filePath,
-		content: content,
+	kind, err := ParseEventKind(event)
+	if err != nil {
+		return nil, err
+	}
+	return c.updateFileContent(filePath, extension, kind, content)
+}
+
+func (c *AssetMin) updateFileContent(filePath, extension string, kind EventKind, content []byte) (*asset, error) {
+	fh := c.assetFor(filePath, extension)
+	if fh == nil {
+		return nil, errors.New("UpdateFileContentInMemory extension: " + extension + " not found " + filePath)
+	}
+
+	wasInvalid := fh.invalidFile(filePath) != nil
+	f := c.newContentFile(fh, filePath, content)
+	if err := fh.UpdateContent(filePath, kind, f); err != nil {
+		return fh, err
+	}
+
+	if kind != EventRemove {
+		c.logValidation(fh, f, wasInvalid)
+	}
+	return fh, nil
+}
+
+// logValidation logs the quarantine of f, or its restore when the file it
+// replaces was quarantined
+func (c *AssetMin) logValidation(fh *asset, f *contentFile, wasInvalid bool) {
+	switch {
+	case f.invalid != nil:
+		d := f.invalid
+		c.log().Warn("file quarantined", LogKeyAsset, fh.fileOutputName, LogKeyPath, f.path,
+			LogKeyLine, d.Line, LogKeyColumn, d.Column, LogKeyError, d.Message)
+	case wasInvalid:
+		c.log().Info("file restored", LogKeyAsset, fh.fileOutputName, LogKeyPath, f.path)
+	}
+}
+
+// withInvalidFile adds to err the diagnostic of filePath when fh built without
+// it, reporting why the file was left out
+func withInvalidFile(fh *asset, filePath string, err error) error {
+	if invalid := fh.invalidFile(filePath); invalid != nil {
+		return errors.Join(invalid, err)
+	}
+	return err
+}
+
+// assetFor returns the asset a source file belongs to, nil if the extension isn't supported
+func (c *AssetMin) assetFor(filePath, extension string) *asset {
+	if fh := c.customAssetFor(filePath); fh != nil {
+		return fh
 	}
 
 	switch extension {
 	case ".css":
-		err := c.mainStyleCssHandler.UpdateContent(filePath, event, file)
-		return c.mainStyleCssHandler, err
-
+		return c.mainStyleCssHandler
 	case ".js":
-		// Remove a leading "use strict" directive from incoming files to avoid
-		// duplicating the directive which we add globally in startCodeJS.
-		file.content = stripLeadingUseStrict(file.content)
-		err := c.mainJsHandler.UpdateContent(filePath, event, file)
-		return c.mainJsHandler, err
-
+		return c.mainJsHandler
 	case ".svg":
 		// Check if it's the favicon file
 		if filepath.Base(filePath) == c.faviconSvgHandler.fileOutputName {
-			err := c.faviconSvgHandler.UpdateContent(filePath, event, file)
-			return c.faviconSvgHandler, err
+			return c.faviconSvgHandler
 		}
 		// Otherwise treat as sprite icon
-		err := c.spriteSvgHandler.UpdateContent(filePath, event, file)
-		return c.spriteSvgHandler, err
-
+		return c.spriteSvgHandler
 	case ".html":
-		err := c.indexHtmlHandler.UpdateContent(filePath, event, file)
-		return c.indexHtmlHandler, err
+		return c.indexHtmlHandler
 	}
+	return nil
+}
 
-	return nil, errors.New("UpdateFileContentInMemory extension: " + extension + " not found " + filePath)
+// unmatchedCustomFile reports whether filePath has an extension supported only
+// through RegisterAsset but no custom asset matches it eg: package.json next to a
+// data.json asset. Such files are skipped like the ones an asset doesn't keep.
+func (c *AssetMin) unmatchedCustomFile(filePath, extension string) bool {
+	return c.assetFor(filePath, extension) == nil && slices.Contains(c.customExtensions(), extension)
 }
 
-// event: create, remove, write, rename
+// loadedFileHash returns the contentHash of the source file loaded from
+// filePath, empty when it isn't loaded
+func (c *AssetMin) loadedFileHash(filePath string) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	fh := c.assetFor(filePath, filepath.Ext(filePath))
+	if fh == nil {
+		return ""
+	}
+	if idx := findFileIndex(fh.contentMiddle, filePath); idx != -1 {
+		return fh.contentMiddle[idx].hash()
+	}
+	return ""
+}
+
+// newContentFile wraps the content of a source file of fh to store it in memory,
+// validated with validateSource. The per file processing happens at build time
+// through the asset transformers.
+func (c *AssetMin) newContentFile(fh *asset, filePath string, content []byte) *contentFile {
+	return &contentFile{path: filePath, content: content, invalid: fh.validateSource(filePath, content)}
+}
+
+// NewFileEvent processes a change reported by a file watcher as a string (create,
+// write, modify, remove, delete or rename). An unknown event returns an error.
+//
+// Deprecated: use NewFileEventKind, the string form is kept during the transition.
 func (c *AssetMin) NewFileEvent(fileName, extension, filePath, event string) error {
+	kind, err := ParseEventKind(event)
+	if err != nil {
+		return err // keeps errors.Is(err, ErrUnknownEvent)
+	}
+	return c.NewFileEventKind(fileName, extension, filePath, kind)
+}
+
+// NewFileEventKind reads filePath and rebuilds the asset it belongs to. An
+// EventRename only carries the old path and is ignored, use RenameFile to move
+// a file keeping its position in the bundle.
+func (c *AssetMin) NewFileEventKind(fileName, extension, filePath string, kind EventKind) error {
 	// Check if filePath matches any of our output paths to avoid infinite recursion
 	if c.isOutputPath(filePath) {
-		//c.writeMessage("Skipping output file:", filePath)
+		return nil
+	}
+
+	if c.isIgnored(filePath, false) {
 		return nil
 	}
 
 	c.mu.Lock()         // Lock the mutex at the beginning
 	defer c.mu.Unlock() // Ensure mutex is unlocked when the function returns
 
-	var e = "NewFileEvent " + extension + " " + event
+	var e = "NewFileEvent " + extension + " " + kind.String() + " "
 	if filePath == "" {
 		return errors.New(e + "filePath is empty")
 	}
+	if c.unmatchedCustomFile(filePath, extension) {
+		return nil
+	}
 
-	c.writeMessage(extension, event, "...", filePath)
+	c.log().Info("file event", LogKeyEvent, kind.String(), LogKeyPath, filePath)
+
+	switch kind {
+	case EventCreate, EventWrite, EventRemove:
+	case EventRename:
+		return nil
+	default:
+		return errors.New(e + ErrUnknownEvent.Error())
+	}
 
 	// Increase sleep duration significantly to allow file system operations (like write after rename) to settle
 	// fail when time is < 10ms
@@ -71,7 +166,7 @@ func (c *AssetMin) NewFileEvent(fileName, extension, filePath, event string) err
 	var err error
 
 	// For delete/remove events, we don't need to read file content since file no longer exists
-	if event == "remove" || event == "delete" {
+	if kind == EventRemove {
 		content = []byte{} // Empty content for delete events
 	} else {
 		// read file content from filePath for other events
@@ -81,7 +176,7 @@ func (c *AssetMin) NewFileEvent(fileName, extension, filePath, event string) err
 		}
 	}
 
-	fh, err := c.UpdateFileContentInMemory(filePath, extension, event, content) // Update contentMiddle
+	fh, err := c.updateFileContent(filePath, extension, kind, content) // Update contentMiddle
 	if err != nil {
 		return errors.New(e + err.Error())
 	}
@@ -89,18 +184,83 @@ func (c *AssetMin) NewFileEvent(fileName, extension, filePath, event string) err
 		return nil
 	}
 
-	return c.processAsset(fh)
+	return withInvalidFile(fh, filePath, c.processAsset(fh, filePath))
 }
 
-func (c *AssetMin) processAsset(fh *asset) error {
+// processAsset rebuilds fh, writes it in DiskMode and notifies the subscribers.
+// file is the source file that triggered the rebuild, empty when there is none.
+func (c *AssetMin) processAsset(fh *asset, file string) error {
+	start := time.Now()
+	fh.mu.RLock()
+	ev := AssetEvent{
+		Asset:   fh.fileOutputName,
+		URL:     fh.URLPath(),
+		OldHash: fh.outputHash(),
+		OldSize: len(fh.cachedMinified),
+		File:    file,
+	}
+	fh.mu.RUnlock()
+
 	// 1. Always regenerate cache
-	if err := fh.RegenerateCache(c.min); err != nil {
-		return err
+	minifyErr := fh.RegenerateCache(c.minifier())
+	ev.Err = minifyErr
+	if ev.Err == nil {
+		// 2. Check the size budget, in strict mode an exceeded one isn't written
+		fh.mu.Lock()
+		rawSize, minifiedSize, gzipSize := fh.bundleSize, len(fh.cachedMinified), fh.outputGzip
+		fh.recordFileSizes()
+		fh.mu.Unlock()
+		ev.Err = c.enforceBudget(fh, c.checkBudget(fh, rawSize, minifiedSize, gzipSize))
+	}
+	if ev.Err == nil {
+		// 3. Write to disk only if DiskMode, keeping the manifest in sync
+		ev.Err = c.writeOutput(fh)
+	}
+
+	fh.mu.RLock()
+	bytesIn := fh.bundleSize
+	gzipSize := fh.outputGzip
+	if minifyErr == nil {
+		ev.NewHash = fh.outputHash()
+		ev.NewSize = len(fh.cachedMinified)
+	}
+	fh.mu.RUnlock()
+	if ev.Err != nil {
+		ev.NewHash, ev.NewSize = "", 0
 	}
+	ev.Duration = time.Since(start)
 
-	// 2. Write to disk only if DiskMode
-	if c.workMode == DiskMode {
-		return FileWrite(fh.outputPath, *bytes.NewBuffer(fh.cachedMinified))
+	attrs := []any{LogKeyAsset, ev.Asset, LogKeyDuration, durationMs(ev.Duration), LogKeyBytesIn, bytesIn}
+	var diag *Diagnostic
+	if errors.As(ev.Err, &diag) && diag.File != "" {
+		// the file with the error, not the one that triggered the rebuild
+		attrs = append(attrs, LogKeyPath, diag.File, LogKeyLine, diag.Line, LogKeyColumn, diag.Column)
+	} else if file != "" {
+		attrs = append(attrs, LogKeyPath, file)
+	}
+	if ev.Err != nil {
+		c.log().Error("asset build failed", append(attrs, LogKeyError, ev.Err)...)
+	} else {
+		c.log().Debug("asset built", append(attrs, LogKeyBytesOut, ev.NewSize)...)
+	}
+
+	c.reportConflicts(fh)
+	c.metrics.recordBuild(ev, bytesIn, gzipSize, minifyErr != nil)
+	c.subs.publish(ev)
+	return ev.Err
+}
+
+// writeOutput writes the cached content of fh in DiskMode, with the manifest.
+// A manifest that can't be written is logged, it doesn't fail the build of fh.
+func (c *AssetMin) writeOutput(fh *asset) error {
+	if c.workMode != DiskMode {
+		return nil
+	}
+	if err := c.OutputFS.WriteFile(fh.outputPath, fh.cachedMinified, c.FileMode); err != nil {
+		return err
+	}
+	if err := c.writeManifest(fh); err != nil {
+		c.log().Warn("manifest write failed", LogKeyPath, c.manifestOutputPath(), LogKeyError, err)
 	}
 	return nil
 }
@@ -108,24 +268,32 @@ func (c *AssetMin) processAsset(fh *asset) error {
 func (c *AssetMin) UnobservedFiles() []string {
 	// Only truly generated/merged files should be unobserved.
 	// index.html and favicon.svg are often user-editable.
-	return []string{
+	files := []string{
 		c.mainStyleCssHandler.outputPath,
 		c.mainJsHandler.outputPath,
 		c.spriteSvgHandler.outputPath,
 	}
+	for _, fh := range c.customAssets {
+		files = append(files, fh.outputPath)
+	}
+	return files
 }
 
 func (c *AssetMin) startCodeJS() (out string, err error) {
 	out = "'use strict';"
 
+	if c.GetRuntimeInitializerJS == nil {
+		return out, nil
+	}
+
 	js, err := c.GetRuntimeInitializerJS() // wasm js code
 	if err != nil {
 		return "", errors.New("startCodeJS " + err.Error())
 	}
 
 	// Remove any leading 'use strict' in the initializer to avoid duplication.
-	// The initializer comes from GetRuntimeInitializerJS and doesn't go through
-	// UpdateFileContentInMemory, so we need to clean it here.
+	// The initializer comes from GetRuntimeInitializerJS and isn't a source file,
+	// so the use strict transformer doesn't see it.
 	clean := stripLeadingUseStrict([]byte(js))
 	out += string(clean)
 
@@ -144,36 +312,32 @@ func (f *asset) hasContentInMemory() bool {
 	return len(f.contentOpen) > 0 || len(f.contentMiddle) > 0 || len(f.contentClose) > 0
 }
 
-// isOutputPath checks if the given file path matches any of our output paths
+// isOutputPath checks if the given file path matches any of our output paths.
+// Both sides are made absolute, so a source root given as an absolute path
+// still finds a relative OutputDir inside it.
 func (c *AssetMin) isOutputPath(filePath string) bool {
 	// Normalize paths for cross-platform comparison
-	normalizedFilePath := filepath.Clean(filePath)
-	cssOutputPath := filepath.Clean(c.mainStyleCssHandler.outputPath)
-	jsOutputPath := filepath.Clean(c.mainJsHandler.outputPath)
-	svgOutputPath := filepath.Clean(c.spriteSvgHandler.outputPath)
-	faviconOutputPath := filepath.Clean(c.faviconSvgHandler.outputPath)
-	htmlHandlerOutputPath := filepath.Clean(c.indexHtmlHandler.outputPath)
-
-	// Case-sensitive comparison first
-	if normalizedFilePath == cssOutputPath ||
-		normalizedFilePath == jsOutputPath ||
-		normalizedFilePath == svgOutputPath ||
-		normalizedFilePath == faviconOutputPath ||
-		normalizedFilePath == htmlHandlerOutputPath {
-		return true
-	}
-
-	// Case-insensitive comparison for cross-platform compatibility
-	normalizedFilePathLower := strings.ToLower(normalizedFilePath)
-	cssOutputPathLower := strings.ToLower(cssOutputPath)
-	jsOutputPathLower := strings.ToLower(jsOutputPath)
-	svgOutputPathLower := strings.ToLower(svgOutputPath)
-	faviconOutputPathLower := strings.ToLower(faviconOutputPath)
-	htmlHandlerOutputPathLower := strings.ToLower(htmlHandlerOutputPath)
-
-	return normalizedFilePathLower == cssOutputPathLower ||
-		normalizedFilePathLower == jsOutputPathLower ||
-		normalizedFilePathLower == svgOutputPathLower ||
-		normalizedFilePathLower == faviconOutputPathLower ||
-		normalizedFilePathLower == htmlHandlerOutputPathLower
+	normalizedFilePath := absPath(filePath)
+
+	outputPaths := []string{c.manifestOutputPath()}
+	for _, fh := range c.assets() {
+		outputPaths = append(outputPaths, fh.outputPath)
+	}
+
+	for _, outputPath := range outputPaths {
+		// Case-insensitive comparison for cross-platform compatibility
+		if strings.EqualFold(normalizedFilePath, absPath(outputPath)) {
+			return true
+		}
+	}
+	return false
+}
+
+// absPath returns the cleaned absolute form of filePath, or the cleaned path
+// when it can't be made absolute
+func absPath(filePath string) string {
+	if abs, err := filepath.Abs(filePath); err == nil {
+		return abs
+	}
+	return filepath.Clean(filePath)
 }
@@ -0,0 +1,212 @@
+package assetmin
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// exportRecordName lists the files written by the last Export so the next one
+// can remove the stale ones without touching anything else in the target
+const exportRecordName = ".assetmin-export.json"
+
+// ExportOptions configures Export
+type ExportOptions struct {
+	Fingerprint bool     // add the content hash to the asset names eg: script.3f2a1b9c.js
+	Passthrough []string // directories whose files are copied verbatim to the root of the target eg: web/static
+}
+
+// ExportResult describes the files of an export, paths are relative to the target directory
+type ExportResult struct {
+	Files    []string // every file written eg: index.html, assets/script.js
+	Removed  []string // stale files left by a previous export
+	Manifest Manifest // details of the exported assets with their final URLs
+}
+
+// Export writes a complete deployable site into targetDir in one pass: index.html,
+// favicon, sprite, CSS, JS, the asset manifest and the passthrough files. Assets
+// are placed at their URL path (AssetsURLPrefix) so the folder can be served as is,
+// and index.html references the exported names. Files from a previous export that
+// are not produced anymore are removed, and nothing is ever written outside targetDir.
+// A passthrough file with the path of a generated one is an error, not an overwrite.
+func (c *AssetMin) Export(targetDir string, opts ExportOptions) (*ExportResult, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	const e = "Export "
+	if targetDir == "" {
+		return nil, errors.New(e + "targetDir is empty")
+	}
+
+	files := map[string][]byte{} // relative path -> content
+	result := &ExportResult{Manifest: Manifest{}}
+
+	add := func(rel string, content []byte) error {
+		rel = filepath.ToSlash(filepath.Clean(rel))
+		switch _, exists := files[rel]; {
+		case !filepath.IsLocal(filepath.FromSlash(rel)):
+			return errors.New("refusing to write outside the target: " + rel)
+		case rel == exportRecordName:
+			return errors.New("file name reserved for the export record: " + rel)
+		case exists:
+			// eg: a passthrough index.html or script.js would replace the generated one
+			return errors.New("file exported twice: " + rel)
+		}
+		files[rel] = content
+		return nil
+	}
+
+	urls := map[string]string{} // current URL -> exported URL
+	for _, fh := range c.assets() {
+		if fh == c.indexHtmlHandler {
+			continue
+		}
+		content, err := c.productionContent(fh)
+		if err != nil {
+			return nil, errors.New(e + fh.fileOutputName + " " + err.Error())
+		}
+		fh.mu.RLock()
+		rawSize := fh.bundleSize
+		fh.mu.RUnlock()
+		gz := gzipSize(content)
+		if err := c.enforceBudget(fh, c.checkBudget(fh, rawSize, len(content), gz)); err != nil {
+			return nil, fmt.Errorf(e+"%w", err) // keeps errors.Is(err, ErrBudgetExceeded)
+		}
+
+		name := fh.fileOutputName
+		if opts.Fingerprint {
+			ext := path.Ext(name)
+			name = strings.TrimSuffix(name, ext) + "." + contentHash(content)[:8] + ext
+		}
+		url := path.Join("/", c.AssetsURLPrefix, name)
+		urls[fh.URLPath()] = url
+
+		if err := add(strings.TrimPrefix(url, "/"), content); err != nil {
+			return nil, errors.New(e + err.Error())
+		}
+		result.Manifest[fh.fileOutputName] = ManifestEntry{
+			URL:        url,
+			OutputPath: filepath.Join(targetDir, filepath.FromSlash(strings.TrimPrefix(url, "/"))),
+			Size:       len(content),
+			GzipSize:   gz,
+			Hash:       contentHash(content),
+			MediaType:  fh.mediatype,
+		}
+	}
+
+	index, err := c.exportIndex(urls)
+	if err != nil {
+		return nil, errors.New(e + err.Error())
+	}
+	if err := add("index.html", index); err != nil {
+		return nil, errors.New(e + err.Error())
+	}
+	result.Manifest[c.indexHtmlHandler.fileOutputName] = ManifestEntry{
+		URL:        "/",
+		OutputPath: filepath.Join(targetDir, "index.html"),
+		Size:       len(index),
+		GzipSize:   gzipSize(index),
+		Hash:       contentHash(index),
+		MediaType:  c.indexHtmlHandler.mediatype,
+	}
+
+	for _, dir := range opts.Passthrough {
+		err := filepath.WalkDir(dir, func(filePath string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() || c.isOutputPath(filePath) {
+				return err
+			}
+			rel, err := filepath.Rel(dir, filePath)
+			if err != nil {
+				return err
+			}
+			content, err := os.ReadFile(filePath)
+			if err != nil {
+				return err
+			}
+			return add(rel, content)
+		})
+		if err != nil {
+			return nil, errors.New(e + "passthrough " + dir + " " + err.Error())
+		}
+	}
+
+	manifest, err := json.MarshalIndent(result.Manifest, "", "  ")
+	if err != nil {
+		return nil, errors.New(e + err.Error())
+	}
+	if err := add(strings.TrimPrefix(c.manifestURLPath(), "/"), manifest); err != nil {
+		return nil, errors.New(e + err.Error())
+	}
+
+	for rel := range files {
+		result.Files = append(result.Files, rel)
+	}
+	sort.Strings(result.Files)
+
+	for _, rel := range result.Files {
+		if err := c.OutputFS.WriteFile(filepath.Join(targetDir, filepath.FromSlash(rel)), files[rel], c.FileMode); err != nil {
+			return nil, errors.New(e + err.Error())
+		}
+	}
+
+	// remove what the previous export wrote and this one didn't
+	recordPath := filepath.Join(targetDir, exportRecordName)
+	if data, err := c.OutputFS.ReadFile(recordPath); err == nil {
+		var previous []string
+		if err := json.Unmarshal(data, &previous); err != nil {
+			return nil, errors.New(e + "reading " + exportRecordName + " " + err.Error())
+		}
+		for _, rel := range previous {
+			if slices.Contains(result.Files, rel) || !filepath.IsLocal(filepath.FromSlash(rel)) {
+				continue
+			}
+			if err := c.OutputFS.Remove(filepath.Join(targetDir, filepath.FromSlash(rel))); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return nil, errors.New(e + err.Error())
+			}
+			result.Removed = append(result.Removed, rel)
+		}
+	}
+
+	record, _ := json.Marshal(result.Files)
+	if err := c.OutputFS.WriteFile(recordPath, record, c.FileMode); err != nil {
+		return nil, errors.New(e + err.Error())
+	}
+
+	return result, nil
+}
+
+// exportIndex builds index.html with the asset URLs replaced by the exported ones
+func (c *AssetMin) exportIndex(urls map[string]string) ([]byte, error) {
+	raw, err := c.indexHtmlHandler.bundle()
+	if err != nil {
+		return nil, err
+	}
+	for from, to := range urls {
+		raw = bytes.ReplaceAll(raw, []byte(`"`+from+`"`), []byte(`"`+to+`"`))
+	}
+
+	minified, err := c.prodMin.Bytes(c.indexHtmlHandler.mediatype, raw)
+	if err != nil {
+		return nil, err
+	}
+	return c.indexHtmlHandler.afterMinify(minified)
+}
+
+// productionContent returns the content of fh minified with the production
+// options, reusing the cache when they are the active ones
+func (c *AssetMin) productionContent(fh *asset) ([]byte, error) {
+	if c.minifier() == c.prodMin {
+		return fh.GetMinifiedContent(c.prodMin)
+	}
+	fh.mu.RLock()
+	defer fh.mu.RUnlock()
+	return fh.minify(c.prodMin)
+}
@@ -0,0 +1,121 @@
+package assetmin
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestExport verifies that Export writes a self-consistent site folder, with
+// fingerprinted names referenced from index.html, and removes stale files.
+func TestExport(t *testing.T) {
+	staticDir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(staticDir, "img"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(staticDir, "img", "logo.png"), []byte("png"), 0644))
+
+	mem := NewMemFS()
+	am := NewAssetMin(&Config{
+		OutputDir:               "public",
+		AssetsURLPrefix:         "/assets/",
+		OutputFS:                mem,
+		GetRuntimeInitializerJS: func() (string, error) { return "", nil },
+	})
+	_, err := am.LoadFS(fstest.MapFS{
+		"app.js":  {Data: []byte("console.log('export');")},
+		"app.css": {Data: []byte(".app { color: red; }")},
+	})
+	require.NoError(t, err)
+
+	target := filepath.Join("dist", "site")
+	read := func(rel string) string {
+		data, err := mem.ReadFile(filepath.Join(target, filepath.FromSlash(rel)))
+		require.NoError(t, err, rel)
+		return string(data)
+	}
+
+	result, err := am.Export(target, ExportOptions{Fingerprint: true, Passthrough: []string{staticDir}})
+	require.NoError(t, err)
+
+	css := result.Manifest["style.css"]
+	js := result.Manifest["script.js"]
+	require.Equal(t, "/assets/style."+contentHash([]byte(".app{color:red}"))[:8]+".css", css.URL)
+	require.Equal(t, ".app{color:red}", read(css.URL[1:]))
+	require.Contains(t, read(js.URL[1:]), "export")
+
+	index := read("index.html")
+	require.Contains(t, index, `href="`+css.URL+`"`)
+	require.Contains(t, index, `src="`+js.URL+`"`)
+	require.Equal(t, "png", read("img/logo.png"))
+
+	var manifest Manifest
+	require.NoError(t, json.Unmarshal([]byte(read("assets/"+ManifestFileName)), &manifest))
+	require.Equal(t, result.Manifest, manifest)
+
+	// nothing is written outside the target nor to OutputDir
+	for _, name := range mem.Files() {
+		require.True(t, filepath.IsLocal(name) && filepath.Dir(name) != "public", name)
+		rel, err := filepath.Rel(target, name)
+		require.NoError(t, err)
+		require.True(t, filepath.IsLocal(rel), name)
+	}
+
+	t.Run("stale_files_are_removed", func(t *testing.T) {
+		am.mu.Lock()
+		_, err := am.UpdateFileContentInMemory("app.css", ".css", "write", []byte(".app { color: blue; }"))
+		am.mu.Unlock()
+		require.NoError(t, err)
+
+		second, err := am.Export(target, ExportOptions{Fingerprint: true})
+		require.NoError(t, err)
+		require.ElementsMatch(t, []string{css.URL[1:], "img/logo.png"}, second.Removed)
+
+		_, err = mem.ReadFile(filepath.Join(target, filepath.FromSlash(css.URL[1:])))
+		require.ErrorIs(t, err, os.ErrNotExist)
+		require.Contains(t, read("index.html"), `href="`+second.Manifest["style.css"].URL+`"`)
+	})
+
+	t.Run("urls_stay_inside_target", func(t *testing.T) {
+		am.AssetsURLPrefix = "../escape"
+		defer func() { am.AssetsURLPrefix = "/assets/" }()
+
+		// URLs are rooted so the prefix can't climb out of the target
+		result, err := am.Export(target, ExportOptions{})
+		require.NoError(t, err)
+		require.Contains(t, result.Files, "escape/script.js")
+		for _, rel := range result.Files {
+			require.True(t, filepath.IsLocal(filepath.FromSlash(rel)), rel)
+		}
+	})
+
+	t.Run("refuses_to_replace_exported_files", func(t *testing.T) {
+		before, err := mem.ReadFile(filepath.Join(target, exportRecordName))
+		require.NoError(t, err)
+
+		for _, tc := range []struct {
+			name, file, prefix, msg string
+		}{
+			{"export record", exportRecordName, "/assets/", "file name reserved for the export record: " + exportRecordName},
+			{"generated index", "index.html", "/assets/", "file exported twice: index.html"},
+			{"asset at the root prefix", "script.js", "/", "file exported twice: script.js"},
+		} {
+			t.Run(tc.name, func(t *testing.T) {
+				am.AssetsURLPrefix = tc.prefix
+				defer func() { am.AssetsURLPrefix = "/assets/" }()
+				static := t.TempDir()
+				require.NoError(t, os.WriteFile(filepath.Join(static, tc.file), []byte("static"), 0644))
+
+				_, err := am.Export(target, ExportOptions{Passthrough: []string{static}})
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tc.msg)
+
+				after, err := mem.ReadFile(filepath.Join(target, exportRecordName))
+				require.NoError(t, err)
+				require.Equal(t, before, after, "nothing is written when a file is refused")
+			})
+		}
+	})
+}
@@ -2,16 +2,29 @@ package assetmin
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
-	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
 )
 
+// defaultFileMode is used for new files when no mode is configured
+const defaultFileMode fs.FileMode = 0644
+
 // pathFile e.g., "theme/htmlMainFileName"
 // data e.g., *bytes.Buffer
-// NOTE: The buffer data will be cleared after writing the file
 func FileWrite(pathFile string, data bytes.Buffer) error {
+	return writeFile(pathFile, data.Bytes(), 0)
+}
+
+// writeFile atomically replaces pathFile with data: the content is written to a
+// temporary file in the same directory which is then renamed into place, so a
+// concurrent reader never sees a half written file. Nothing is written when the
+// file already holds the same content. perm 0 keeps the mode of an existing
+// file (0644 for new ones), any other value is enforced.
+func writeFile(pathFile string, data []byte, perm fs.FileMode) error {
 	const e = "FileWrite "
 
 	// Ensure the directory exists before creating the file
@@ -20,17 +33,48 @@ func FileWrite(pathFile string, data bytes.Buffer) error {
 		return errors.New(e + "while creating directory " + err.Error())
 	}
 
-	dst, err := os.Create(pathFile)
+	mode := perm
+	if info, err := os.Stat(pathFile); err == nil {
+		if mode == 0 {
+			mode = info.Mode().Perm()
+		}
+		if existing, err := os.ReadFile(pathFile); err == nil && contentHash(existing) == contentHash(data) {
+			if info.Mode().Perm() != mode {
+				return os.Chmod(pathFile, mode)
+			}
+			return nil
+		}
+	}
+	if mode == 0 {
+		mode = defaultFileMode
+	}
+
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(pathFile)+".*.tmp")
 	if err != nil {
 		return errors.New(e + "while creating file " + err.Error())
 	}
-	defer dst.Close()
+	tmpName := tmp.Name()
 
-	// Copy the uploaded contentFile to the filesystem at the specified destination
-	_, err = io.Copy(dst, &data)
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Chmod(mode)
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmpName, pathFile)
+	}
 	if err != nil {
+		os.Remove(tmpName)
 		return errors.New(e + "failed to write the file " + pathFile + " to the destination " + err.Error())
 	}
 
 	return nil
 }
+
+// contentHash returns the hex encoded sha256 of data
+func contentHash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
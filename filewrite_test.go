@@ -0,0 +1,53 @@
+package assetmin
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestFileWrite verifies that writes are atomic, skipped when the content is
+// unchanged and that file modes are preserved or enforced.
+func TestFileWrite(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "public", "script.js")
+
+	// new files get the default mode
+	require.NoError(t, FileWrite(target, *bytes.NewBufferString("console.log(1)")))
+	info, err := os.Stat(target)
+	require.NoError(t, err)
+	require.Equal(t, defaultFileMode, info.Mode().Perm())
+
+	// identical content is not rewritten
+	past := time.Now().Add(-time.Hour).Truncate(time.Second)
+	require.NoError(t, os.Chtimes(target, past, past))
+	require.NoError(t, FileWrite(target, *bytes.NewBufferString("console.log(1)")))
+	info, err = os.Stat(target)
+	require.NoError(t, err)
+	require.True(t, info.ModTime().Equal(past), "unchanged content must not touch the file")
+
+	// changed content keeps the existing mode
+	require.NoError(t, os.Chmod(target, 0600))
+	require.NoError(t, FileWrite(target, *bytes.NewBufferString("console.log(2)")))
+	info, err = os.Stat(target)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0600), info.Mode().Perm())
+	content, err := os.ReadFile(target)
+	require.NoError(t, err)
+	require.Equal(t, "console.log(2)", string(content))
+
+	// a configured mode is enforced even when the content is unchanged
+	require.NoError(t, writeFile(target, []byte("console.log(2)"), 0640))
+	info, err = os.Stat(target)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0640), info.Mode().Perm())
+
+	// no temporary files are left behind
+	entries, err := os.ReadDir(filepath.Dir(target))
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+}
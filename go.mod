@@ -4,7 +4,10 @@ go 1.23.0
 
 toolchain go1.23.8
 
-require github.com/tdewolff/minify/v2 v2.23.11
+require (
+	github.com/tdewolff/minify/v2 v2.23.11
+	github.com/tdewolff/parse/v2 v2.8.2-0.20250806174018-50048bb39781
+)
 
 require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
@@ -12,7 +15,4 @@ require (
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
 
-require (
-	github.com/stretchr/testify v1.10.0
-	github.com/tdewolff/parse/v2 v2.8.2-0.20250806174018-50048bb39781 // indirect
-)
+require github.com/stretchr/testify v1.10.0
@@ -1,28 +1,60 @@
 package assetmin
 
 import (
+	"bytes"
 	"net/http"
 )
 
 // RegisterRoutes registers the HTTP handlers for all assets.
 func (c *AssetMin) RegisterRoutes(mux *http.ServeMux) {
-	mux.HandleFunc(c.indexHtmlHandler.URLPath(), c.serveAsset(c.indexHtmlHandler))
-	mux.HandleFunc(c.mainStyleCssHandler.URLPath(), c.serveAsset(c.mainStyleCssHandler))
-	mux.HandleFunc(c.mainJsHandler.URLPath(), c.serveAsset(c.mainJsHandler))
-	mux.HandleFunc(c.spriteSvgHandler.URLPath(), c.serveAsset(c.spriteSvgHandler))
-	mux.HandleFunc(c.faviconSvgHandler.URLPath(), c.serveAsset(c.faviconSvgHandler))
+	for _, fh := range c.assets() {
+		mux.HandleFunc(fh.URLPath(), c.serveAsset(fh))
+	}
+	mux.HandleFunc(c.manifestURLPath(), c.serveManifest)
 }
 
 func (c *AssetMin) serveAsset(asset *asset) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		content, err := asset.GetMinifiedContent(c.min)
+		content, hash, _, err := asset.minifiedOutput(c.minifier())
 		if err != nil {
 			http.Error(w, "Error getting minified content", http.StatusInternalServerError)
 			return
 		}
 
+		tag := etag(hash)
+		unchanged := notModified(r, tag)
+		c.metrics.update(asset.fileOutputName, func(am *AssetMetrics) {
+			am.HTTPRequests++
+			if unchanged {
+				am.HTTPNotModified++
+			}
+		})
+
+		// no-cache lets the browser keep a copy but revalidate it on every request
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("ETag", tag)
+		if unchanged {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
 		w.Header().Set("Content-Type", asset.mediatype)
-		w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
 		_, _ = w.Write(content)
 	}
 }
+
+// etag returns the strong entity tag of the content served for hash
+func etag(hash string) string {
+	return `"` + hash[:16] + `"`
+}
+
+// notModified reports whether the request already holds the content tagged tag
+func notModified(r *http.Request, tag string) bool {
+	for _, candidate := range bytes.Split([]byte(r.Header.Get("If-None-Match")), []byte(",")) {
+		candidate = bytes.TrimSpace(bytes.TrimPrefix(bytes.TrimSpace(candidate), []byte("W/")))
+		if string(candidate) == tag || string(candidate) == "*" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,306 @@
+package assetmin
+
+import (
+	"bufio"
+	"bytes"
+	"maps"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// IgnoreFileName is the file read from each source root with extra ignore
+// patterns, one per line using the gitignore syntax
+const IgnoreFileName = ".assetminignore"
+
+// ignorePattern is a single gitignore style rule eg: "*.test.js", "node_modules/", "!keep.js", "/drafts"
+type ignorePattern struct {
+	segments []string // pattern split by "/" eg: "assets/**/*.js" -> ["assets", "**", "*.js"]
+	negate   bool     // "!pattern" re-includes a path excluded by a previous rule
+	dirOnly  bool     // "pattern/" only matches directories
+	anchored bool     // the pattern contains a "/" so it is matched from the root
+}
+
+type ignoreRules []ignorePattern
+
+// parseIgnoreRules parses gitignore style lines, blank lines and # comments are skipped
+func parseIgnoreRules(lines []string) ignoreRules {
+	var rules ignoreRules
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		var p ignorePattern
+		if strings.HasPrefix(line, "!") {
+			p.negate = true
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			p.dirOnly = true
+			line = strings.TrimRight(line, "/")
+		}
+		if strings.Contains(line, "/") {
+			p.anchored = true
+			line = strings.TrimPrefix(line, "/")
+		}
+		if line == "" {
+			continue
+		}
+		p.segments = strings.Split(line, "/")
+		rules = append(rules, p)
+	}
+	return rules
+}
+
+// parseIgnoreFile parses the content of an .assetminignore file
+func parseIgnoreFile(content []byte) ignoreRules {
+	var lines []string
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	return parseIgnoreRules(lines)
+}
+
+// ignored reports whether rel (slash separated, relative to the root) is excluded.
+// As in git, a path inside an excluded directory can't be re-included.
+func (r ignoreRules) ignored(rel string, isDir bool) bool {
+	if len(r) == 0 || rel == "" || rel == "." {
+		return false
+	}
+	segments := strings.Split(rel, "/")
+	for i := 1; i < len(segments); i++ {
+		if r.match(segments[:i], true) {
+			return true
+		}
+	}
+	return r.match(segments, isDir)
+}
+
+// match applies the rules in order, the last matching rule wins
+func (r ignoreRules) match(segments []string, isDir bool) bool {
+	ignored := false
+	for _, p := range r {
+		if p.dirOnly && !isDir {
+			continue
+		}
+		var ok bool
+		if p.anchored {
+			ok = matchSegments(p.segments, segments)
+		} else {
+			ok, _ = path.Match(p.segments[0], segments[len(segments)-1])
+		}
+		if ok {
+			ignored = !p.negate
+		}
+	}
+	return ignored
+}
+
+// matchSegments matches path segments against pattern segments where "**"
+// stands for any number of directories
+func matchSegments(pattern, name []string) bool {
+	if len(pattern) == 0 {
+		return len(name) == 0
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(name); i++ {
+			if matchSegments(pattern[1:], name[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(name) == 0 {
+		return false
+	}
+	if ok, _ := path.Match(pattern[0], name[0]); !ok {
+		return false
+	}
+	return matchSegments(pattern[1:], name[1:])
+}
+
+// ignoreSet holds the rules from Config.IgnorePatterns plus the ones read from
+// the .assetminignore file of every known source root. Both are evaluated as
+// one list with the global rules first, so a root can re-include with
+// "!pattern" what a global pattern excluded. The files of Config.IgnoreFiles
+// are ignored by their absolute path, whatever the rules say.
+type ignoreSet struct {
+	mu     sync.Mutex
+	global ignoreRules
+	roots  map[string]ignoreRules // absolute root -> rules from its ignore file
+	files  map[string]bool        // absolute paths from Config.IgnoreFiles
+	logged map[string]bool        // ignored paths already reported
+}
+
+func newIgnoreSet(patterns, files []string) *ignoreSet {
+	s := &ignoreSet{
+		global: parseIgnoreRules(patterns),
+		roots:  map[string]ignoreRules{},
+		files:  map[string]bool{},
+		logged: map[string]bool{},
+	}
+	for _, f := range files {
+		if abs, err := filepath.Abs(f); err == nil {
+			s.files[abs] = true
+		}
+	}
+	return s
+}
+
+// addRoot registers a source root and loads its ignore file once
+func (s *ignoreSet) addRoot(root string) {
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.roots[abs]; ok {
+		return
+	}
+	content, _ := os.ReadFile(filepath.Join(abs, IgnoreFileName))
+	s.roots[abs] = parseIgnoreFile(content)
+}
+
+// rootList returns the known source roots, sorted
+func (s *ignoreSet) rootList() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Sorted(maps.Keys(s.roots))
+}
+
+// reloadRoot reads the ignore file of root again, the paths it ignores are
+// reported again too
+func (s *ignoreSet) reloadRoot(root string) {
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		return
+	}
+	content, _ := os.ReadFile(filepath.Join(abs, IgnoreFileName))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.roots[abs] = parseIgnoreFile(content)
+	s.logged = map[string]bool{}
+}
+
+// ignored checks filePath against the global rules followed by the rules of
+// the root that contains it. Paths outside every root are matched as given.
+func (s *ignoreSet) ignored(filePath string, isDir bool) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if abs, err := filepath.Abs(filePath); err == nil && !isDir && s.files[abs] {
+		return true
+	}
+	rel, root := s.relative(filePath)
+	return s.global.then(s.roots[root]).ignored(rel, isDir)
+}
+
+// then returns r followed by next, the rules of next win when both match
+func (r ignoreRules) then(next ignoreRules) ignoreRules {
+	if len(next) == 0 {
+		return r
+	}
+	return append(slices.Clip(r), next...)
+}
+
+// relativePath returns filePath relative to the deepest known root containing
+// it, in slash form. Paths outside every root are returned cleaned as given.
+func (s *ignoreSet) relativePath(filePath string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rel, _ := s.relative(filePath)
+	return rel
+}
+
+// relative is relativePath returning the root too, the caller must hold the lock
+func (s *ignoreSet) relative(filePath string) (rel, root string) {
+	rel = filepath.ToSlash(filepath.Clean(filePath))
+
+	abs, err := filepath.Abs(filePath)
+	if err != nil {
+		return rel, ""
+	}
+	// the deepest root containing the file wins
+	for r := range s.roots {
+		relToRoot, err := filepath.Rel(r, abs)
+		if err != nil || relToRoot == ".." || strings.HasPrefix(relToRoot, ".."+string(filepath.Separator)) {
+			continue
+		}
+		if len(r) > len(root) {
+			root = r
+			rel = filepath.ToSlash(relToRoot)
+		}
+	}
+	return rel, root
+}
+
+// firstReport returns true only the first time a path is reported
+func (s *ignoreSet) firstReport(filePath string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.logged[filePath] {
+		return false
+	}
+	s.logged[filePath] = true
+	return true
+}
+
+// isIgnored reports whether filePath matches the ignore rules, logging each
+// ignored path once at debug level
+func (c *AssetMin) isIgnored(filePath string, isDir bool) bool {
+	if !c.ignore.ignored(filePath, isDir) {
+		return false
+	}
+	c.reportIgnored(filePath)
+	return true
+}
+
+// isIgnoredFS is isIgnored for paths of an fs.FS, rules come from its own ignore file
+func (c *AssetMin) isIgnoredFS(rules ignoreRules, filePath string, isDir bool) bool {
+	if !c.ignore.global.then(rules).ignored(filePath, isDir) {
+		return false
+	}
+	c.reportIgnored(filePath)
+	return true
+}
+
+// reloadIgnoreFile applies the current ignore file of root: the loaded source
+// files it now excludes are removed from their assets, the ones it re-includes
+// are picked up as created by the next poll of the watcher
+func (c *AssetMin) reloadIgnoreFile(root string) {
+	c.ignore.reloadRoot(root)
+	c.log().Info("ignore rules reloaded", LogKeyPath, filepath.Join(root, IgnoreFileName))
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, fh := range c.assets() {
+		var dropped []string
+		for _, f := range fh.contentMiddle {
+			if c.isIgnored(f.path, false) {
+				dropped = append(dropped, f.path)
+			}
+		}
+		for _, filePath := range dropped {
+			_ = fh.UpdateContent(filePath, EventRemove, &contentFile{path: filePath}) // never fails for EventRemove
+		}
+		if len(dropped) > 0 {
+			_ = c.processAsset(fh, "") // a failed build is logged by processAsset
+		}
+	}
+}
+
+func (c *AssetMin) reportIgnored(filePath string) {
+	if c.ignore.firstReport(filePath) {
+		c.log().Debug("ignored", LogKeyPath, filePath)
+	}
+}
@@ -0,0 +1,156 @@
+package assetmin
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIgnoreRules(t *testing.T) {
+	rules := parseIgnoreRules([]string{
+		"# editor and test files",
+		"*.test.js",
+		"*.swp",
+		"node_modules/",
+		"/drafts",
+		"assets/**/legacy.css",
+		"*.css",
+		"!keep.css",
+	})
+
+	cases := []struct {
+		path    string
+		isDir   bool
+		ignored bool
+	}{
+		{"modules/app/app.test.js", false, true},
+		{"modules/app/app.js", false, false},
+		{"modules/app/.app.js.swp", false, true},
+		{"node_modules", true, true},
+		{"modules/node_modules/lib/index.js", false, true},
+		{"node_modules.js", false, false},
+		{"drafts/new.js", false, true},
+		{"modules/drafts/new.js", false, false},
+		{"assets/a/b/legacy.css", false, true},
+		{"theme/main.css", false, true},
+		{"theme/keep.css", false, false},
+	}
+	for _, tc := range cases {
+		require.Equal(t, tc.ignored, rules.ignored(tc.path, tc.isDir), tc.path)
+	}
+}
+
+// TestIgnorePatterns verifies that Config.IgnorePatterns and the .assetminignore
+// file of a source root apply to events, directory scans and the watcher.
+func TestIgnorePatterns(t *testing.T) {
+	env := setupTestEnv("ignore_patterns", t)
+	defer env.CleanDirectory()
+	env.CreateModulesDir()
+
+	var logs []string
+	am := env.AssetsHandler
+	am.LogLevel = slog.LevelDebug
+	am.Logger = func(message ...any) {
+		logs = append(logs, fmt.Sprintln(message...))
+	}
+	am.ignore = newIgnoreSet([]string{"*.test.js"}, nil)
+
+	require.NoError(t, os.WriteFile(filepath.Join(env.ModulesDir, IgnoreFileName), []byte("drafts/\n"), 0644))
+
+	files := map[string]string{
+		filepath.Join(env.ModulesDir, "app.js"):            "console.log('app');",
+		filepath.Join(env.ModulesDir, "app.test.js"):       "console.log('test');",
+		filepath.Join(env.ModulesDir, "drafts", "wip.js"):  "console.log('draft');",
+		filepath.Join(env.ModulesDir, "drafts", "wip.css"): ".draft{color:red}",
+	}
+	for path, content := range files {
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+
+	t.Run("scan", func(t *testing.T) {
+		summary, err := am.ScanDirectories(env.ModulesDir)
+		require.NoError(t, err)
+		require.Equal(t, []string{filepath.Join(env.ModulesDir, "app.js")}, summary["script.js"])
+		require.Empty(t, summary["style.css"])
+	})
+
+	t.Run("events", func(t *testing.T) {
+		draft := filepath.Join(env.ModulesDir, "drafts", "wip.js")
+		require.NoError(t, am.NewFileEvent("wip.js", ".js", draft, "write"))
+		require.NoError(t, am.NewFileEvent("wip.js", ".js", draft, "write"))
+
+		content, err := am.mainJsHandler.GetMinifiedContent(am.minifier())
+		require.NoError(t, err)
+		require.Contains(t, string(content), "app")
+		require.NotContains(t, string(content), "draft")
+		require.NotContains(t, string(content), "test")
+
+		reported := 0
+		for _, line := range logs {
+			if strings.Contains(line, "debug:") && strings.Contains(line, draft) {
+				reported++
+			}
+		}
+		require.Equal(t, 1, reported, "ignored paths must be logged once")
+	})
+
+	t.Run("watcher", func(t *testing.T) {
+		w := &watcher{am: am, roots: []string{env.ModulesDir}}
+		w.files = w.snapshot()
+		require.Len(t, w.files, 1)
+		require.Contains(t, w.files, filepath.Join(env.ModulesDir, "app.js"))
+	})
+}
+
+// TestIgnoreRuleOrder verifies that the ignore file of a root comes after the
+// global patterns, so its negations re-include what they excluded.
+func TestIgnoreRuleOrder(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(root, IgnoreFileName), []byte("!keep.gen.js\n"), 0644))
+
+	s := newIgnoreSet([]string{"*.gen.js"}, nil)
+	s.addRoot(root)
+	require.True(t, s.ignored(filepath.Join(root, "other.gen.js"), false))
+	require.False(t, s.ignored(filepath.Join(root, "keep.gen.js"), false))
+	require.True(t, s.ignored(filepath.Join(t.TempDir(), "keep.gen.js"), false), "the negation only applies inside its root")
+}
+
+// TestIgnoreFileReload verifies that the watcher applies the changes of an
+// ignore file to the files already loaded.
+func TestIgnoreFileReload(t *testing.T) {
+	env := setupTestEnv("ignore_reload", t)
+	defer env.CleanDirectory()
+	env.CreateModulesDir()
+	am := env.AssetsHandler
+
+	for name, content := range map[string]string{"app.js": "console.log('app');", "extra.js": "console.log('extra');"} {
+		require.NoError(t, os.WriteFile(filepath.Join(env.ModulesDir, name), []byte(content), 0644))
+	}
+	_, err := am.ScanDirectories(env.ModulesDir)
+	require.NoError(t, err)
+
+	w := &watcher{am: am, roots: []string{env.ModulesDir}}
+	w.files = w.snapshot()
+	w.ignores = w.ignoreFiles()
+	js := func() string {
+		content, err := am.mainJsHandler.GetMinifiedContent(am.minifier())
+		require.NoError(t, err)
+		return string(content)
+	}
+
+	ignoreFile := filepath.Join(env.ModulesDir, IgnoreFileName)
+	require.NoError(t, os.WriteFile(ignoreFile, []byte("extra.js\n"), 0644))
+	w.poll()
+	require.Contains(t, js(), "app")
+	require.NotContains(t, js(), "extra")
+
+	require.NoError(t, os.Remove(ignoreFile))
+	w.poll()
+	require.Contains(t, js(), "extra")
+}
@@ -20,6 +20,8 @@ func TestJSRenameFlow(t *testing.T) {
 	// Setup test environment
 	env := setupTestEnv("js_rename_flow", t)
 	env.AssetsHandler.SetWorkMode(DiskMode)
+	// legacy rename flow: rename event ignored, guessed on the following create
+	env.AssetsHandler.RenameByContent = true
 	//defer env.CleanDirectory()
 
 	// Prepare three initial JS files
@@ -117,6 +119,7 @@ func TestJSRenameScenarios(t *testing.T) {
 			name: "pure_rename_same_content",
 			scenario: func(t *testing.T, env *TestEnvironment) {
 				env.AssetsHandler.SetWorkMode(DiskMode)
+				env.AssetsHandler.RenameByContent = true
 				// Setup three initial JS files
 				file1Path := filepath.Join(env.BaseDir, "modules", "module1", "script1.js")
 				file2Path := filepath.Join(env.BaseDir, "modules", "module2", "script2.js")
@@ -157,6 +160,7 @@ func TestJSRenameScenarios(t *testing.T) {
 			name: "rename_with_different_content",
 			scenario: func(t *testing.T, env *TestEnvironment) {
 				env.AssetsHandler.SetWorkMode(DiskMode)
+				env.AssetsHandler.RenameByContent = true
 				// Setup three initial JS files
 				file1Path := filepath.Join(env.BaseDir, "modules", "module1", "script1.js")
 				file2Path := filepath.Join(env.BaseDir, "modules", "module2", "script2.js")
@@ -199,6 +203,7 @@ func TestJSRenameScenarios(t *testing.T) {
 			name: "duplicate_content_rename",
 			scenario: func(t *testing.T, env *TestEnvironment) {
 				env.AssetsHandler.SetWorkMode(DiskMode)
+				env.AssetsHandler.RenameByContent = true
 				// Two files with same content, rename one -> both entries should remain
 				file1Path := filepath.Join(env.BaseDir, "modules", "module1", "script1.js")
 				file2Path := filepath.Join(env.BaseDir, "modules", "module2", "script2.js")
@@ -238,6 +243,7 @@ func TestJSRenameScenarios(t *testing.T) {
 			name: "rename_then_write",
 			scenario: func(t *testing.T, env *TestEnvironment) {
 				env.AssetsHandler.SetWorkMode(DiskMode)
+				env.AssetsHandler.RenameByContent = true
 				// Rename then write new content (editor save after rename)
 				file1Path := filepath.Join(env.BaseDir, "modules", "module1", "script1.js")
 				file2Path := filepath.Join(env.BaseDir, "modules", "module2", "script2.js")
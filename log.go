@@ -0,0 +1,104 @@
+package assetmin
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// Attribute keys of the structured log records, stable so log pipelines can
+// filter and index them
+const (
+	LogKeyAsset    = "asset"       // output name eg: script.js
+	LogKeyPath     = "path"        // source or output file
+	LogKeyOldPath  = "old_path"    // previous path of a renamed file
+	LogKeyEvent    = "event"       // file event eg: create
+	LogKeyDuration = "duration_ms" // rebuild time in milliseconds
+	LogKeyBytesIn  = "bytes_in"    // bundle size before minification
+	LogKeyBytesOut = "bytes_out"   // minified size
+	LogKeyError    = "error"
+	LogKeyLine     = "line"     // line of a diagnostic in its source file
+	LogKeyColumn   = "column"   // column of a diagnostic in its source file
+	LogKeyConflict = "conflict" // kind of a duplicate eg: css-selector
+	LogKeyFiles    = "files"    // source files involved in a duplicate
+	LogKeyDetail   = "detail"   // description of a duplicate
+)
+
+// log returns Config.Slog, or an adapter writing to Config.Logger when it's not set
+func (c *AssetMin) log() *slog.Logger {
+	if c.Slog != nil {
+		return c.Slog
+	}
+	return c.legacyLog
+}
+
+// durationMs converts d to the value of LogKeyDuration
+func durationMs(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+// loggerHandler adapts the records to Config.Logger as positional values:
+// a level prefix for non info records, the message and key=value attributes.
+// Records below Config.LogLevel are dropped, debug ones by default. Config.Logger
+// and Config.LogLevel are read on every record so they can be changed at any time.
+type loggerHandler struct {
+	cfg    *Config
+	attrs  []slog.Attr
+	prefix string // group prefix of the attribute keys
+}
+
+func (h *loggerHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return h.cfg.Logger != nil && level >= h.cfg.LogLevel
+}
+
+func (h *loggerHandler) Handle(_ context.Context, r slog.Record) error {
+	logger := h.cfg.Logger
+	if logger == nil {
+		return nil
+	}
+
+	var out []any
+	switch {
+	case r.Level < slog.LevelInfo:
+		out = append(out, "debug:")
+	case r.Level >= slog.LevelError:
+		out = append(out, "error:")
+	case r.Level >= slog.LevelWarn:
+		out = append(out, "warn:")
+	}
+	out = append(out, r.Message)
+
+	for _, a := range h.attrs {
+		out = append(out, formatAttr("", a))
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		out = append(out, formatAttr(h.prefix, a))
+		return true
+	})
+
+	logger(out...)
+	return nil
+}
+
+func (h *loggerHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	nh := *h
+	nh.attrs = append([]slog.Attr{}, h.attrs...)
+	for _, a := range attrs {
+		nh.attrs = append(nh.attrs, slog.Attr{Key: h.prefix + a.Key, Value: a.Value})
+	}
+	return &nh
+}
+
+func (h *loggerHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	nh := *h
+	nh.prefix = h.prefix + name + "."
+	return &nh
+}
+
+func formatAttr(prefix string, a slog.Attr) string {
+	return fmt.Sprintf("%s%s=%v", prefix, a.Key, a.Value.Resolve().Any())
+}
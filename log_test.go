@@ -0,0 +1,81 @@
+package assetmin
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestSlogRecords verifies the stable attribute keys of the structured records.
+func TestSlogRecords(t *testing.T) {
+	env := setupTestEnv("slog_records", t)
+	defer env.CleanDirectory()
+
+	var buf bytes.Buffer
+	env.AssetsHandler.Slog = slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	path := filepath.Join(env.ModulesDir, "log", "app.js")
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, os.WriteFile(path, []byte("console.log('logged');"), 0644))
+	// a file valid on its own that breaks the bundle, an invalid one is quarantined
+	_, err := env.AssetsHandler.UpdateFileContentInMemory(filepath.Join(env.ModulesDir, "log", "first.js"), ".js", "create", []byte("let dup = 1;"))
+	require.NoError(t, err)
+	require.NoError(t, env.AssetsHandler.NewFileEventKind("app.js", ".js", path, EventCreate))
+
+	require.NoError(t, os.WriteFile(path, []byte("let dup = 2;"), 0644))
+	require.Error(t, env.AssetsHandler.NewFileEventKind("app.js", ".js", path, EventWrite))
+
+	records := map[string]map[string]any{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var rec map[string]any
+		require.NoError(t, json.Unmarshal([]byte(line), &rec))
+		records[rec["msg"].(string)] = rec
+	}
+
+	event := records["file event"]
+	require.Equal(t, "INFO", event["level"])
+	require.Equal(t, "write", event[LogKeyEvent])
+	require.Equal(t, path, event[LogKeyPath])
+
+	built := records["asset built"]
+	require.Equal(t, "DEBUG", built["level"])
+	require.Equal(t, "script.js", built[LogKeyAsset])
+	for _, key := range []string{LogKeyDuration, LogKeyBytesIn, LogKeyBytesOut} {
+		require.Contains(t, built, key)
+	}
+
+	failed := records["asset build failed"]
+	require.Equal(t, "ERROR", failed["level"])
+	require.Equal(t, path, failed[LogKeyPath])
+	require.NotEmpty(t, failed[LogKeyError])
+}
+
+// TestLoggerAdapter verifies that Config.Logger keeps receiving the records,
+// from info up unless Config.LogLevel asks for more.
+func TestLoggerAdapter(t *testing.T) {
+	var lines []string
+	config := &Config{Logger: func(message ...any) { lines = append(lines, fmt.Sprintln(message...)) }}
+	am := NewAssetMin(config)
+
+	am.log().Debug("hidden", LogKeyPath, "a.test.js")
+	require.Empty(t, lines, "debug records are dropped by default")
+
+	config.LogLevel = slog.LevelDebug
+	am.log().With(LogKeyAsset, "style.css").WithGroup("detail").Warn("too big", "limit", 10)
+	am.log().Debug("ignored", LogKeyPath, "a.test.js")
+	require.Equal(t, []string{
+		"warn: too big asset=style.css detail.limit=10\n",
+		"debug: ignored path=a.test.js\n",
+	}, lines)
+
+	config.Logger = nil
+	am.log().Error("nobody listens")
+	require.Len(t, lines, 2)
+}
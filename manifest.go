@@ -0,0 +1,104 @@
+package assetmin
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"path"
+	"path/filepath"
+)
+
+// ManifestFileName is the name of the manifest served next to the assets and
+// written to OutputDir in DiskMode
+const ManifestFileName = "asset-manifest.json"
+
+// ManifestEntry describes one generated output
+type ManifestEntry struct {
+	URL        string `json:"url"`        // eg: /assets/script.js
+	OutputPath string `json:"outputPath"` // eg: web/public/script.js
+	Size       int    `json:"size"`       // minified size in bytes
+	GzipSize   int    `json:"gzipSize"`   // minified size compressed with gzip, the one SizeBudget.Gzip checks
+	Hash       string `json:"hash"`       // sha256 of the minified content
+	MediaType  string `json:"mediaType"`  // eg: text/javascript
+}
+
+// Manifest maps the logical output names eg: "script.js", "index.html" to their details
+type Manifest map[string]ManifestEntry
+
+// Manifest returns the details of every generated output, building the assets
+// whose cache is not valid yet.
+func (c *AssetMin) Manifest() (Manifest, error) {
+	manifest := Manifest{}
+	var errs []error
+
+	for _, fh := range c.assets() {
+		content, hash, gzipSize, err := fh.minifiedOutput(c.minifier())
+		if err != nil {
+			errs = append(errs, errors.New(fh.fileOutputName+" "+err.Error()))
+			continue
+		}
+		manifest[fh.fileOutputName] = fh.manifestEntry(content, hash, gzipSize)
+	}
+
+	return manifest, errors.Join(errs...)
+}
+
+// manifestEntry describes fh serving content, whose contentHash is hash
+func (h *asset) manifestEntry(content []byte, hash string, gzipSize int) ManifestEntry {
+	return ManifestEntry{
+		URL:        h.URLPath(),
+		OutputPath: h.outputPath,
+		Size:       len(content),
+		GzipSize:   gzipSize,
+		Hash:       hash,
+		MediaType:  h.mediatype,
+	}
+}
+
+// manifestJSON returns the manifest encoded as indented JSON
+func (c *AssetMin) manifestJSON() ([]byte, error) {
+	manifest, err := c.Manifest()
+	if err != nil {
+		return nil, errors.New("manifest " + err.Error())
+	}
+	return json.MarshalIndent(manifest, "", "  ")
+}
+
+// manifestURLPath returns the HTTP route of the manifest eg: /assets/asset-manifest.json
+func (c *AssetMin) manifestURLPath() string {
+	return path.Join("/", c.AssetsURLPrefix, ManifestFileName)
+}
+
+// manifestOutputPath returns the path of the manifest in OutputDir
+func (c *AssetMin) manifestOutputPath() string {
+	return filepath.Join(c.OutputDir, ManifestFileName)
+}
+
+// writeManifest records fh as written and writes the manifest of the outputs
+// written so far to OutputDir. No asset is built here: an asset that was never
+// written, or whose last build failed, keeps its previous entry or none.
+// The caller must hold c.mu.
+func (c *AssetMin) writeManifest(fh *asset) error {
+	if c.written == nil {
+		c.written = Manifest{}
+	}
+	c.written[fh.fileOutputName] = fh.manifestEntry(fh.cachedMinified, fh.outputSum, fh.outputGzip)
+
+	data, err := json.MarshalIndent(c.written, "", "  ")
+	if err != nil {
+		return err
+	}
+	return c.OutputFS.WriteFile(c.manifestOutputPath(), data, c.FileMode)
+}
+
+func (c *AssetMin) serveManifest(w http.ResponseWriter, r *http.Request) {
+	data, err := c.manifestJSON()
+	if err != nil {
+		http.Error(w, "Error building asset manifest", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
+	_, _ = w.Write(data)
+}
@@ -0,0 +1,125 @@
+package assetmin
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestManifest verifies the typed manifest API, its HTTP route and the
+// asset-manifest.json written to OutputDir in DiskMode.
+func TestManifest(t *testing.T) {
+	mem := NewMemFS()
+	am := NewAssetMin(&Config{
+		OutputDir:               "public",
+		AssetsURLPrefix:         "/assets/",
+		OutputFS:                mem,
+		GetRuntimeInitializerJS: func() (string, error) { return "", nil },
+	})
+	am.SetWorkMode(DiskMode)
+
+	_, err := am.LoadFS(fstest.MapFS{
+		"app.css": {Data: []byte(".app { color: red; }")},
+	})
+	require.NoError(t, err)
+
+	manifest, err := am.Manifest()
+	require.NoError(t, err)
+	require.Len(t, manifest, 5)
+	require.Equal(t, ManifestEntry{
+		URL:        "/assets/style.css",
+		OutputPath: filepath.Join("public", "style.css"),
+		Size:       len(".app{color:red}"),
+		GzipSize:   gzipSize([]byte(".app{color:red}")),
+		Hash:       contentHash([]byte(".app{color:red}")),
+		MediaType:  "text/css",
+	}, manifest["style.css"])
+	require.Equal(t, "/", manifest["index.html"].URL)
+	require.Equal(t, "image/svg+xml", manifest["sprite.svg"].MediaType)
+
+	t.Run("written_in_disk_mode", func(t *testing.T) {
+		data, err := mem.ReadFile(filepath.Join("public", ManifestFileName))
+		require.NoError(t, err)
+
+		var written Manifest
+		require.NoError(t, json.Unmarshal(data, &written))
+
+		// favicon.svg has no source, it's never written so it isn't listed
+		expected := Manifest{}
+		for name, entry := range manifest {
+			if name != "favicon.svg" {
+				expected[name] = entry
+			}
+		}
+		require.Equal(t, expected, written)
+	})
+
+	t.Run("served_over_http", func(t *testing.T) {
+		mux := http.NewServeMux()
+		am.RegisterRoutes(mux)
+		server := httptest.NewServer(mux)
+		defer server.Close()
+
+		resp, err := http.Get(server.URL + "/assets/" + ManifestFileName)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		var served Manifest
+		require.NoError(t, json.Unmarshal(body, &served))
+		require.Equal(t, manifest, served)
+	})
+
+	t.Run("manifest_is_an_output_path", func(t *testing.T) {
+		require.True(t, am.isOutputPath(filepath.Join("public", ManifestFileName)))
+	})
+}
+
+// TestManifestIsolation verifies that writing the manifest builds nothing: a
+// broken asset doesn't fail the events of the others and keeps its last entry.
+func TestManifestIsolation(t *testing.T) {
+	mem := NewMemFS()
+	am := NewAssetMin(&Config{OutputDir: "public", OutputFS: mem}) // no runtime initializer
+	am.SetWorkMode(DiskMode)
+
+	_, err := am.LoadFS(fstest.MapFS{
+		"a.js":    {Data: []byte("let dup = 1;")},
+		"app.css": {Data: []byte(".app { color: red; }")},
+	})
+	require.NoError(t, err)
+	readManifest := func() Manifest {
+		data, err := mem.ReadFile(filepath.Join("public", ManifestFileName))
+		require.NoError(t, err)
+		var written Manifest
+		require.NoError(t, json.Unmarshal(data, &written))
+		return written
+	}
+	before := readManifest()["script.js"]
+	require.NotEmpty(t, before.Hash)
+
+	// valid on its own, it breaks script.js declaring dup again
+	fh, err := am.UpdateFileContentInMemory("b.js", ".js", "create", []byte("let dup = 2;"))
+	require.NoError(t, err)
+	require.Error(t, am.processAsset(fh, "b.js"))
+
+	fh, err = am.UpdateFileContentInMemory("app.css", ".css", "write", []byte(".app { color: blue; }"))
+	require.NoError(t, err)
+	require.NoError(t, am.processAsset(fh, "app.css"), "a broken script.js must not fail style.css")
+
+	written := readManifest()
+	require.Equal(t, before, written["script.js"], "the last written script.js is kept")
+	css, err := mem.ReadFile(filepath.Join("public", "style.css"))
+	require.NoError(t, err)
+	require.Contains(t, string(css), "blue")
+	require.Equal(t, contentHash(css), written["style.css"].Hash)
+	require.NotContains(t, written, "favicon.svg")
+}
@@ -0,0 +1,181 @@
+package assetmin
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// rebuildBuckets are the upper bounds in seconds of the rebuild duration histogram
+var rebuildBuckets = []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5}
+
+// Histogram counts observations in cumulative buckets like Prometheus does
+type Histogram struct {
+	Bounds []float64 // upper bounds of the buckets, in seconds
+	Counts []uint64  // observations <= the bound of the same index
+	Sum    float64   // sum of every observation, in seconds
+	Count  uint64    // number of observations
+}
+
+func (h *Histogram) observe(v float64) {
+	if h.Bounds == nil {
+		h.Bounds = rebuildBuckets
+		h.Counts = make([]uint64, len(rebuildBuckets))
+	}
+	for i, bound := range h.Bounds {
+		if v <= bound {
+			h.Counts[i]++
+		}
+	}
+	h.Sum += v
+	h.Count++
+}
+
+// AssetMetrics are the counters of one asset since NewAssetMin
+type AssetMetrics struct {
+	Events          uint64    // file events that changed the asset
+	Rebuilds        uint64    // builds, including the failed ones
+	MinifyErrors    uint64    // builds that failed to minify or transform
+	BudgetErrors    uint64    // builds over their SizeBudget in strict mode
+	WriteErrors     uint64    // DiskMode writes that failed
+	RebuildDuration Histogram // time spent per build
+	RawSize         int       // bytes of the last bundle before minification
+	MinifiedSize    int       // bytes of the last minified output
+	GzipSize        int       // bytes of the last minified output compressed with gzip
+	HTTPRequests    uint64    // requests served by RegisterRoutes
+	HTTPNotModified uint64    // requests answered with 304 Not Modified
+}
+
+// metrics holds the AssetMetrics by output name
+type metrics struct {
+	mu     sync.Mutex
+	assets map[string]*AssetMetrics
+}
+
+func (m *metrics) update(asset string, fn func(am *AssetMetrics)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.assets == nil {
+		m.assets = map[string]*AssetMetrics{}
+	}
+	am, ok := m.assets[asset]
+	if !ok {
+		am = &AssetMetrics{}
+		m.assets[asset] = am
+	}
+	fn(am)
+}
+
+// recordBuild stores the result of processAsset, gzipSize is the one computed
+// with the output, see setOutput
+func (m *metrics) recordBuild(ev AssetEvent, rawSize, gzipSize int, minifyFailed bool) {
+	m.update(ev.Asset, func(am *AssetMetrics) {
+		if ev.File != "" {
+			am.Events++
+		}
+		am.Rebuilds++
+		am.RebuildDuration.observe(ev.Duration.Seconds())
+		switch {
+		case minifyFailed:
+			am.MinifyErrors++
+		case errors.Is(ev.Err, ErrBudgetExceeded):
+			am.BudgetErrors++
+		case ev.Err != nil:
+			am.WriteErrors++
+		}
+		if ev.Err == nil {
+			am.RawSize = rawSize
+			am.MinifiedSize = ev.NewSize
+			am.GzipSize = gzipSize
+		}
+	})
+}
+
+// gzipSize returns the size of data compressed with gzip at the default level
+func gzipSize(data []byte) int {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	_, _ = zw.Write(data)
+	_ = zw.Close()
+	return buf.Len()
+}
+
+// Metrics returns a copy of the counters of every asset built or served so far,
+// by output name eg: "script.js"
+func (c *AssetMin) Metrics() map[string]AssetMetrics {
+	c.metrics.mu.Lock()
+	defer c.metrics.mu.Unlock()
+
+	out := make(map[string]AssetMetrics, len(c.metrics.assets))
+	for name, am := range c.metrics.assets {
+		cp := *am
+		cp.RebuildDuration.Counts = append([]uint64(nil), am.RebuildDuration.Counts...)
+		out[name] = cp
+	}
+	return out
+}
+
+// MetricsHandler serves Metrics in the Prometheus text exposition format, mount
+// it where the scraper expects it eg: mux.Handle("/metrics", am.MetricsHandler())
+func (c *AssetMin) MetricsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		writeMetrics(w, c.Metrics())
+	})
+}
+
+// writeMetrics writes m in the Prometheus text format, sorted by asset
+func writeMetrics(w io.Writer, m map[string]AssetMetrics) {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	family := func(name, typ, help string, value func(am AssetMetrics) float64) {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+		for _, asset := range names {
+			fmt.Fprintf(w, "%s{asset=%q} %s\n", name, asset, formatFloat(value(m[asset])))
+		}
+	}
+
+	family("assetmin_events_total", "counter", "File events that changed the asset.", func(am AssetMetrics) float64 { return float64(am.Events) })
+	family("assetmin_rebuilds_total", "counter", "Builds of the asset, including the failed ones.", func(am AssetMetrics) float64 { return float64(am.Rebuilds) })
+	family("assetmin_minify_errors_total", "counter", "Builds that failed to minify or transform.", func(am AssetMetrics) float64 { return float64(am.MinifyErrors) })
+	family("assetmin_budget_errors_total", "counter", "Builds over their size budget in strict mode.", func(am AssetMetrics) float64 { return float64(am.BudgetErrors) })
+	family("assetmin_write_errors_total", "counter", "DiskMode writes that failed.", func(am AssetMetrics) float64 { return float64(am.WriteErrors) })
+	family("assetmin_http_requests_total", "counter", "HTTP requests served.", func(am AssetMetrics) float64 { return float64(am.HTTPRequests) })
+	family("assetmin_http_not_modified_total", "counter", "HTTP requests answered with 304 Not Modified.", func(am AssetMetrics) float64 { return float64(am.HTTPNotModified) })
+
+	fmt.Fprint(w, "# HELP assetmin_size_bytes Size of the last build by kind: raw, minified or gzip.\n# TYPE assetmin_size_bytes gauge\n")
+	for _, asset := range names {
+		am := m[asset]
+		for _, kind := range []struct {
+			name string
+			size int
+		}{{"raw", am.RawSize}, {"minified", am.MinifiedSize}, {"gzip", am.GzipSize}} {
+			fmt.Fprintf(w, "assetmin_size_bytes{asset=%q,kind=%q} %d\n", asset, kind.name, kind.size)
+		}
+	}
+
+	fmt.Fprint(w, "# HELP assetmin_rebuild_duration_seconds Time spent building the asset.\n# TYPE assetmin_rebuild_duration_seconds histogram\n")
+	for _, asset := range names {
+		h := m[asset].RebuildDuration
+		for i, bound := range h.Bounds {
+			fmt.Fprintf(w, "assetmin_rebuild_duration_seconds_bucket{asset=%q,le=%q} %d\n", asset, formatFloat(bound), h.Counts[i])
+		}
+		fmt.Fprintf(w, "assetmin_rebuild_duration_seconds_bucket{asset=%q,le=\"+Inf\"} %d\n", asset, h.Count)
+		fmt.Fprintf(w, "assetmin_rebuild_duration_seconds_sum{asset=%q} %s\n", asset, formatFloat(h.Sum))
+		fmt.Fprintf(w, "assetmin_rebuild_duration_seconds_count{asset=%q} %d\n", asset, h.Count)
+	}
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
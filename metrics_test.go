@@ -0,0 +1,83 @@
+package assetmin
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestMetrics verifies the build and HTTP counters, the ETag revalidation and
+// the Prometheus text output.
+func TestMetrics(t *testing.T) {
+	env := setupTestEnv("metrics", t)
+	defer env.CleanDirectory()
+	am := env.AssetsHandler
+
+	path := filepath.Join(env.ModulesDir, "metrics", "app.css")
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, os.WriteFile(path, []byte(".app { color: red; }"), 0644))
+	require.NoError(t, am.NewFileEventKind("app.css", ".css", path, EventCreate))
+	require.NoError(t, am.NewFileEventKind("app.css", ".css", path, EventWrite))
+
+	// valid on its own, it breaks the bundle declaring dup again
+	_, err := am.UpdateFileContentInMemory(filepath.Join(env.ModulesDir, "metrics", "first.js"), ".js", "create", []byte("let dup = 1;"))
+	require.NoError(t, err)
+	broken := filepath.Join(env.ModulesDir, "metrics", "broken.js")
+	require.NoError(t, os.WriteFile(broken, []byte("let dup = 2;"), 0644))
+	require.Error(t, am.NewFileEventKind("broken.js", ".js", broken, EventCreate))
+
+	m := am.Metrics()
+	css := m["style.css"]
+	require.Equal(t, uint64(2), css.Events)
+	require.Equal(t, uint64(2), css.Rebuilds)
+	require.Equal(t, uint64(2), css.RebuildDuration.Count)
+	require.Equal(t, len(".app { color: red; }\n"), css.RawSize)
+	require.Equal(t, len(".app{color:red}"), css.MinifiedSize)
+	require.Equal(t, gzipSize([]byte(".app{color:red}")), css.GzipSize)
+	require.Equal(t, uint64(1), m["script.js"].MinifyErrors)
+
+	mux := http.NewServeMux()
+	am.RegisterRoutes(mux)
+	mux.Handle("/metrics", am.MetricsHandler())
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", "/style.css", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	tag := rec.Header().Get("ETag")
+	require.NotEmpty(t, tag)
+
+	req := httptest.NewRequest("GET", "/style.css", nil)
+	req.Header.Set("If-None-Match", tag)
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusNotModified, rec.Code)
+	require.Empty(t, rec.Body.String())
+
+	css = am.Metrics()["style.css"]
+	require.Equal(t, uint64(2), css.HTTPRequests)
+	require.Equal(t, uint64(1), css.HTTPNotModified)
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	body, _ := io.ReadAll(rec.Body)
+	text := string(body)
+	for _, line := range []string{
+		"# TYPE assetmin_events_total counter",
+		`assetmin_events_total{asset="style.css"} 2`,
+		`assetmin_minify_errors_total{asset="script.js"} 1`,
+		`assetmin_http_not_modified_total{asset="style.css"} 1`,
+		`assetmin_size_bytes{asset="style.css",kind="minified"} 15`,
+		`assetmin_rebuild_duration_seconds_bucket{asset="style.css",le="+Inf"} 2`,
+		`assetmin_rebuild_duration_seconds_count{asset="style.css"} 2`,
+	} {
+		require.Contains(t, text, line+"\n")
+	}
+	require.Less(t, strings.Index(text, `{asset="script.js"}`), strings.Index(text, `{asset="style.css"}`))
+}
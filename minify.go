@@ -0,0 +1,167 @@
+package assetmin
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+
+	"github.com/tdewolff/minify/v2"
+	"github.com/tdewolff/minify/v2/css"
+	"github.com/tdewolff/minify/v2/html"
+	"github.com/tdewolff/minify/v2/js"
+	"github.com/tdewolff/minify/v2/svg"
+)
+
+// MinifyOptions configures the minifier of each media type for one build
+// profile, nil fields keep the defaults. See the tdewolff/minify packages for the
+// meaning of every option.
+type MinifyOptions struct {
+	HTML *html.Minifier // default keeps document tags, end tags, quotes and whitespace
+	CSS  *css.Minifier  // eg: Precision
+	JS   *js.Minifier   // eg: Version (ECMAScript year, 0 = latest), KeepVarNames
+	SVG  *svg.Minifier  // eg: Precision
+}
+
+// defaultHTMLMinifier keeps the markup readable and the document structure that
+// index.html templates rely on
+var defaultHTMLMinifier = html.Minifier{
+	KeepDocumentTags: true,
+	KeepEndTags:      true,
+	KeepWhitespace:   true,
+	KeepQuotes:       true,
+}
+
+// Validate reports option combinations known to break the output
+func (o *MinifyOptions) Validate() error {
+	if o == nil {
+		return nil
+	}
+	const e = "MinifyOptions "
+	var errs []error
+
+	if o.HTML != nil && (o.HTML.TemplateDelims[0] == "") != (o.HTML.TemplateDelims[1] == "") {
+		errs = append(errs, errors.New(e+"HTML.TemplateDelims needs both the open and close delimiter"))
+	}
+	if o.CSS != nil {
+		if o.CSS.Inline {
+			errs = append(errs, errors.New(e+"CSS.Inline minifies style attributes and breaks bundled stylesheets"))
+		}
+		if o.CSS.Precision < 0 {
+			errs = append(errs, errors.New(e+"CSS.Precision can't be negative"))
+		}
+	}
+	if o.JS != nil {
+		if o.JS.Version != 0 && o.JS.Version < 2015 {
+			errs = append(errs, errors.New(e+"JS.Version "+strconv.Itoa(o.JS.Version)+" must be 0 (latest) or an ECMAScript year from 2015"))
+		}
+		if o.JS.Precision < 0 {
+			errs = append(errs, errors.New(e+"JS.Precision can't be negative"))
+		}
+	}
+	if o.SVG != nil {
+		if o.SVG.Inline {
+			errs = append(errs, errors.New(e+"SVG.Inline drops the xmlns the sprite and favicon need as standalone files"))
+		}
+		if o.SVG.Precision < 0 {
+			errs = append(errs, errors.New(e+"SVG.Precision can't be negative"))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// newMinifier returns a minify.M configured with o, the defaults fill the nil fields
+func newMinifier(o *MinifyOptions) *minify.M {
+	if o == nil {
+		o = &MinifyOptions{}
+	}
+
+	htmlMin := defaultHTMLMinifier
+	if o.HTML != nil {
+		htmlMin = *o.HTML
+	}
+	cssMin, jsMin, svgMin := css.Minifier{}, js.Minifier{}, svg.Minifier{}
+	if o.CSS != nil {
+		cssMin = *o.CSS
+	}
+	if o.JS != nil {
+		jsMin = *o.JS
+	}
+	if o.SVG != nil {
+		svgMin = *o.SVG
+	}
+
+	m := minify.New()
+	m.Add("text/html", &htmlMin)
+	m.Add("text/css", &cssMin)
+	m.AddRegexp(jsMediaType, &jsMin)
+	m.Add("image/svg+xml", &svgMin)
+	return m
+}
+
+// minifyKey identifies the options used to build the caches, so a snapshot made
+// with other options isn't reused
+func minifyKey(o *MinifyOptions) string {
+	if o == nil {
+		o = &MinifyOptions{}
+	}
+	data, _ := json.Marshal(o)
+	return contentHash(data)
+}
+
+// SetMinifyOptions replaces the development (MemoryMode) and production
+// (DiskMode and Export) minifier options. Invalid options return an error and
+// leave the current ones in place; every asset is minified again on its next build.
+func (c *AssetMin) SetMinifyOptions(dev, prod *MinifyOptions) error {
+	if err := errors.Join(dev.Validate(), prod.Validate()); err != nil {
+		return errors.New("SetMinifyOptions " + err.Error())
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.MinifyDev, c.MinifyProd = dev, prod
+	c.initMinifiers()
+	c.useMinifier()
+	return nil
+}
+
+// initMinifiers builds the minifiers of both profiles, sharing one when the
+// options are the same so switching the work mode keeps the caches
+func (c *AssetMin) initMinifiers() {
+	c.devMin = newMinifier(c.MinifyDev)
+	c.prodMin = c.devMin
+	if minifyKey(c.MinifyDev) != minifyKey(c.MinifyProd) {
+		c.prodMin = newMinifier(c.MinifyProd)
+	}
+}
+
+// useMinifier activates the minifier of the current work mode. When it changes
+// the caches are dropped since they were produced with other options.
+// The caller must hold c.mu.
+func (c *AssetMin) useMinifier() {
+	m := c.devMin
+	if c.workMode == DiskMode {
+		m = c.prodMin
+	}
+	if c.min.Swap(m) == m {
+		return
+	}
+	for _, fh := range c.assets() {
+		fh.mu.Lock()
+		fh.cacheValid = false
+		fh.sourceHash = ""
+		fh.mu.Unlock()
+	}
+}
+
+// minifier returns the minifier of the current work mode
+func (c *AssetMin) minifier() *minify.M {
+	return c.min.Load()
+}
+
+// minifierKey identifies the active minifier options
+func (c *AssetMin) minifierKey() string {
+	if c.minifier() == c.prodMin {
+		return minifyKey(c.MinifyProd)
+	}
+	return minifyKey(c.MinifyDev)
+}
@@ -0,0 +1,91 @@
+package assetmin
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tdewolff/minify/v2/css"
+	"github.com/tdewolff/minify/v2/html"
+	"github.com/tdewolff/minify/v2/js"
+	"github.com/tdewolff/minify/v2/svg"
+)
+
+func TestMinifyOptionsValidate(t *testing.T) {
+	var nilOptions *MinifyOptions
+	require.NoError(t, nilOptions.Validate())
+	require.NoError(t, (&MinifyOptions{JS: &js.Minifier{Version: 2020}, CSS: &css.Minifier{Precision: 3}}).Validate())
+
+	err := (&MinifyOptions{
+		HTML: &html.Minifier{TemplateDelims: [2]string{"{{", ""}},
+		CSS:  &css.Minifier{Inline: true},
+		JS:   &js.Minifier{Version: 5},
+		SVG:  &svg.Minifier{Precision: -1},
+	}).Validate()
+	require.Error(t, err)
+	for _, want := range []string{"TemplateDelims", "CSS.Inline", "JS.Version", "SVG.Precision"} {
+		require.Contains(t, err.Error(), want)
+	}
+}
+
+// TestMinifyProfiles verifies that MemoryMode uses the development options,
+// DiskMode and Export the production ones.
+func TestMinifyProfiles(t *testing.T) {
+	env := setupTestEnv("minify_profiles", t)
+	defer env.CleanDirectory()
+	am := env.AssetsHandler
+
+	require.NoError(t, am.SetMinifyOptions(nil, &MinifyOptions{
+		HTML: &html.Minifier{},
+		CSS:  &css.Minifier{Precision: 2},
+	}))
+
+	path := filepath.Join(env.ModulesDir, "profile", "app.css")
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, os.WriteFile(path, []byte(".app { width: 33.3333%; }"), 0644))
+	_, err := am.ScanDirectories(env.ModulesDir)
+	require.NoError(t, err)
+
+	dev, err := am.mainStyleCssHandler.GetMinifiedContent(am.minifier())
+	require.NoError(t, err)
+	require.Equal(t, ".app{width:33.3333%}", string(dev))
+	devIndex, err := am.indexHtmlHandler.GetMinifiedContent(am.minifier())
+	require.NoError(t, err)
+
+	export, err := am.Export(filepath.Join(env.BaseDir, "dist"), ExportOptions{})
+	require.NoError(t, err)
+	require.Equal(t, len(".app{width:33%}"), export.Manifest["style.css"].Size)
+
+	am.SetWorkMode(DiskMode)
+	prod, err := am.mainStyleCssHandler.GetMinifiedContent(am.minifier())
+	require.NoError(t, err)
+	require.Equal(t, ".app{width:33%}", string(prod))
+	prodIndex, err := am.indexHtmlHandler.GetMinifiedContent(am.minifier())
+	require.NoError(t, err)
+	require.Less(t, len(prodIndex), len(devIndex))
+	require.False(t, strings.Contains(string(prodIndex), "</body>"))
+
+	t.Run("invalid_options", func(t *testing.T) {
+		require.Error(t, am.SetMinifyOptions(&MinifyOptions{CSS: &css.Minifier{Inline: true}}, nil))
+		// the previous options stay in place
+		prod, err := am.mainStyleCssHandler.GetMinifiedContent(am.minifier())
+		require.NoError(t, err)
+		require.Equal(t, ".app{width:33%}", string(prod))
+
+		// invalid Config options are reported and replaced by the defaults
+		var logs []string
+		other := NewAssetMin(&Config{
+			OutputDir: env.PublicDir,
+			Logger: func(message ...any) {
+				logs = append(logs, fmt.Sprintln(message...))
+			},
+			MinifyProd: &MinifyOptions{JS: &js.Minifier{Version: 1}},
+		})
+		require.Nil(t, other.MinifyProd)
+		require.Len(t, logs, 1)
+		require.Contains(t, logs[0], "JS.Version")
+	})
+}
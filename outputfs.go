@@ -0,0 +1,108 @@
+package assetmin
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// OutputFS is the writable filesystem behind every file AssetMin writes
+// (bundles, favicon, index and sidecar files). Names are full output paths
+// eg: web/public/script.js and a WriteFile perm of 0 means "keep the current mode".
+type OutputFS interface {
+	MkdirAll(dir string, perm fs.FileMode) error
+	WriteFile(name string, data []byte, perm fs.FileMode) error
+	ReadFile(name string) ([]byte, error)
+	Remove(name string) error
+}
+
+// OSFS writes to the local disk, it is the default OutputFS
+type OSFS struct{}
+
+func (OSFS) MkdirAll(dir string, perm fs.FileMode) error {
+	return os.MkdirAll(dir, perm)
+}
+
+// WriteFile creates the parent directories if they don't exist and replaces the
+// file atomically, skipping the write when the content is unchanged
+func (OSFS) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	return writeFile(name, data, perm)
+}
+
+func (OSFS) ReadFile(name string) ([]byte, error) {
+	return os.ReadFile(name)
+}
+
+func (OSFS) Remove(name string) error {
+	return os.Remove(name)
+}
+
+// MemFS is an in-memory OutputFS, eg: to check the output in tests
+type MemFS struct {
+	mu    sync.RWMutex
+	files map[string]memFile
+}
+
+type memFile struct {
+	data []byte
+	perm fs.FileMode
+}
+
+// NewMemFS returns an empty in-memory OutputFS
+func NewMemFS() *MemFS {
+	return &MemFS{files: map[string]memFile{}}
+}
+
+// MkdirAll does nothing, directories are implicit in memory
+func (m *MemFS) MkdirAll(dir string, perm fs.FileMode) error {
+	return nil
+}
+
+func (m *MemFS) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	name = filepath.Clean(name)
+	if perm == 0 {
+		perm = defaultFileMode
+		if f, ok := m.files[name]; ok {
+			perm = f.perm
+		}
+	}
+	m.files[name] = memFile{data: append([]byte(nil), data...), perm: perm}
+	return nil
+}
+
+func (m *MemFS) ReadFile(name string) ([]byte, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	f, ok := m.files[filepath.Clean(name)]
+	if !ok {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: fs.ErrNotExist}
+	}
+	return append([]byte(nil), f.data...), nil
+}
+
+func (m *MemFS) Remove(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	name = filepath.Clean(name)
+	if _, ok := m.files[name]; !ok {
+		return &fs.PathError{Op: "remove", Path: name, Err: fs.ErrNotExist}
+	}
+	delete(m.files, name)
+	return nil
+}
+
+// Files returns the sorted names of the files written so far
+func (m *MemFS) Files() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	names := make([]string, 0, len(m.files))
+	for name := range m.files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
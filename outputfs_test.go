@@ -0,0 +1,52 @@
+package assetmin
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestOutputFS verifies that DiskMode writes go through Config.OutputFS so the
+// output can be redirected to memory without touching OutputDir.
+func TestOutputFS(t *testing.T) {
+	outputDir := filepath.Join("test", "output_fs", "public")
+	mem := NewMemFS()
+
+	am := NewAssetMin(&Config{
+		OutputDir:               outputDir,
+		OutputFS:                mem,
+		GetRuntimeInitializerJS: func() (string, error) { return "", nil },
+	})
+	am.SetWorkMode(DiskMode)
+	am.EnsureOutputDirectoryExists()
+
+	_, err := am.LoadFS(fstest.MapFS{
+		"app.js":      {Data: []byte("console.log('mem');")},
+		"app.css":     {Data: []byte(".mem { color: red; }")},
+		"favicon.svg": {Data: []byte(`<svg xmlns="http://www.w3.org/2000/svg"><circle r="1"/></svg>`)},
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, []string{
+		filepath.Join(outputDir, ManifestFileName),
+		filepath.Join(outputDir, "favicon.svg"),
+		filepath.Join(outputDir, "index.html"),
+		filepath.Join(outputDir, "script.js"),
+		filepath.Join(outputDir, "sprite.svg"),
+		filepath.Join(outputDir, "style.css"),
+	}, mem.Files())
+
+	css, err := mem.ReadFile(filepath.Join(outputDir, "style.css"))
+	require.NoError(t, err)
+	require.Equal(t, ".mem{color:red}", string(css))
+
+	_, err = os.Stat(outputDir)
+	require.True(t, os.IsNotExist(err), "nothing must be written to the real disk")
+
+	require.NoError(t, mem.Remove(filepath.Join(outputDir, "style.css")))
+	_, err = mem.ReadFile(filepath.Join(outputDir, "style.css"))
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
@@ -0,0 +1,90 @@
+package assetmin
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// RenameFile moves a source file from oldPath to newPath. The content is read
+// again from newPath and the entry keeps its position in the bundle, so the
+// output order doesn't change and files with identical content (eg: empty stubs)
+// are never confused. When both paths belong to different assets, or one of them
+// is ignored or unsupported, the old entry is removed and the new one created.
+func (c *AssetMin) RenameFile(oldPath, newPath string) error {
+	const e = "RenameFile "
+	if oldPath == "" || newPath == "" {
+		return errors.New(e + "oldPath or newPath is empty")
+	}
+
+	skipped := func(filePath string) bool {
+		return c.isOutputPath(filePath) || c.isIgnored(filePath, false)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.log().Info("file event", LogKeyEvent, EventRename.String(), LogKeyPath, newPath, LogKeyOldPath, oldPath)
+
+	var from, to *asset
+	wasInvalid := false
+	if !skipped(oldPath) {
+		if from = c.assetFor(oldPath, filepath.Ext(oldPath)); from != nil {
+			wasInvalid = from.invalidFile(oldPath) != nil
+		}
+	}
+
+	var file *contentFile
+	if !skipped(newPath) {
+		if to = c.assetFor(newPath, filepath.Ext(newPath)); to != nil {
+			content, err := os.ReadFile(newPath)
+			if err != nil {
+				return errors.New(e + err.Error())
+			}
+			file = c.newContentFile(to, newPath, content)
+		}
+	}
+
+	if from != nil && from == to && from.RenameContent(oldPath, newPath, file) {
+		c.logValidation(to, file, wasInvalid)
+		return withInvalidFile(to, newPath, c.processAsset(from, newPath))
+	}
+
+	var errs []error
+	if from != nil {
+		if err := from.UpdateContent(oldPath, EventRemove, &contentFile{path: oldPath}); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if to != nil {
+		if err := to.UpdateContent(newPath, EventCreate, file); err != nil {
+			errs = append(errs, err)
+		} else {
+			c.logValidation(to, file, from == to && wasInvalid)
+		}
+	}
+
+	for _, fh := range []*asset{from, to} {
+		if fh == nil {
+			continue
+		}
+		if err := c.processAsset(fh, newPath); err != nil {
+			errs = append(errs, err)
+		}
+		if from == to {
+			break // same asset, processed once
+		}
+	}
+
+	if to != nil {
+		if invalid := to.invalidFile(newPath); invalid != nil {
+			errs = append(errs, invalid)
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf(e+"%w", errors.Join(errs...)) // keeps errors.As(err, *Diagnostic)
+	}
+	return nil
+}
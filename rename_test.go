@@ -0,0 +1,122 @@
+package assetmin
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestRenameFile verifies that an explicit rename moves the entry keeping its
+// position, never confuses files with identical content and picks up content
+// changed during the rename.
+func TestRenameFile(t *testing.T) {
+	env := setupTestEnv("rename_file", t)
+	env.AssetsHandler.SetWorkMode(DiskMode)
+	defer env.CleanDirectory()
+
+	write := func(path, content string) {
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+	move := func(oldPath, newPath string) {
+		require.NoError(t, os.Rename(oldPath, newPath))
+		require.NoError(t, env.AssetsHandler.RenameFile(oldPath, newPath))
+	}
+	paths := func(fh *asset) (out []string) {
+		for _, f := range fh.contentMiddle {
+			out = append(out, filepath.Base(f.path))
+		}
+		return out
+	}
+
+	first := filepath.Join(env.ModulesDir, "a", "first.js")
+	stubA := filepath.Join(env.ModulesDir, "a", "stub-a.js")
+	stubB := filepath.Join(env.ModulesDir, "b", "stub-b.js")
+	last := filepath.Join(env.ModulesDir, "c", "last.js")
+	write(first, "console.log('first');")
+	write(stubA, "")
+	write(stubB, "")
+	write(last, "console.log('last');")
+
+	_, err := env.AssetsHandler.ScanDirectories(env.ModulesDir)
+	require.NoError(t, err)
+	js := env.AssetsHandler.mainJsHandler
+	require.Equal(t, []string{"first.js", "stub-a.js", "stub-b.js", "last.js"}, paths(js))
+
+	t.Run("identical_content_keeps_each_entry", func(t *testing.T) {
+		renamed := filepath.Join(env.ModulesDir, "b", "stub-b2.js")
+		move(stubB, renamed)
+		stubB = renamed
+		require.Equal(t, []string{"first.js", "stub-a.js", "stub-b2.js", "last.js"}, paths(js))
+	})
+
+	t.Run("content_changed_during_rename", func(t *testing.T) {
+		renamed := filepath.Join(env.ModulesDir, "a", "first-renamed.js")
+		require.NoError(t, os.Rename(first, renamed))
+		write(renamed, "console.log('rewritten');")
+		require.NoError(t, env.AssetsHandler.RenameFile(first, renamed))
+		first = renamed
+
+		require.Equal(t, []string{"first-renamed.js", "stub-a.js", "stub-b2.js", "last.js"}, paths(js))
+		out, err := os.ReadFile(env.MainJsPath)
+		require.NoError(t, err)
+		require.Contains(t, string(out), "rewritten")
+		require.NotContains(t, string(out), "'first'")
+		require.Equal(t, 1, strings.Count(string(out), "last"))
+	})
+
+	t.Run("move_to_another_asset", func(t *testing.T) {
+		renamed := filepath.Join(env.ModulesDir, "c", "last.css")
+		require.NoError(t, os.Rename(last, renamed))
+		write(renamed, ".last { color: red; }")
+		require.NoError(t, env.AssetsHandler.RenameFile(last, renamed))
+
+		require.Equal(t, []string{"first-renamed.js", "stub-a.js", "stub-b2.js"}, paths(js))
+		css, err := os.ReadFile(env.MainCssPath)
+		require.NoError(t, err)
+		require.Contains(t, string(css), ".last{color:red}")
+	})
+
+	t.Run("unknown_old_path_is_created", func(t *testing.T) {
+		created := filepath.Join(env.ModulesDir, "d", "new.js")
+		write(created, "console.log('new');")
+		require.NoError(t, env.AssetsHandler.RenameFile(filepath.Join(env.ModulesDir, "d", "new.txt"), created))
+		require.Equal(t, []string{"first-renamed.js", "stub-a.js", "stub-b2.js", "new.js"}, paths(js))
+	})
+
+	t.Run("rename_to_invalid_file_is_quarantined", func(t *testing.T) {
+		var logs []string
+		previous := env.AssetsHandler.Logger
+		env.AssetsHandler.Logger = func(message ...any) {
+			logs = append(logs, fmt.Sprintln(message...))
+		}
+		defer func() { env.AssetsHandler.Logger = previous }()
+
+		broken := filepath.Join(env.ModulesDir, "b", "broken.js")
+		require.NoError(t, os.Rename(stubB, broken))
+		write(broken, "function (")
+		err := env.AssetsHandler.RenameFile(stubB, broken)
+		var diag *Diagnostic
+		require.True(t, errors.As(err, &diag), "the rename reports why the file was left out")
+		require.True(t, diag.Quarantined)
+		require.Equal(t, broken, diag.File)
+		require.Contains(t, strings.Join(logs, ""), "warn: file quarantined asset=script.js path="+broken)
+		require.Equal(t, []string{"first-renamed.js", "stub-a.js", "broken.js", "new.js"}, paths(js))
+
+		fixed := filepath.Join(env.ModulesDir, "b", "fixed.js")
+		require.NoError(t, os.Rename(broken, fixed))
+		write(fixed, "console.log('fixed');")
+		require.NoError(t, env.AssetsHandler.RenameFile(broken, fixed))
+		require.Contains(t, strings.Join(logs, ""), "file restored asset=script.js path="+fixed)
+		require.Equal(t, []string{"first-renamed.js", "stub-a.js", "fixed.js", "new.js"}, paths(js))
+	})
+
+	t.Run("missing_new_path", func(t *testing.T) {
+		require.Error(t, env.AssetsHandler.RenameFile(stubA, filepath.Join(env.ModulesDir, "a", "missing.js")))
+	})
+}
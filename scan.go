@@ -0,0 +1,156 @@
+package assetmin
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// ScanSummary lists the source files loaded into each bundle,
+// keyed by the bundle output name eg: "script.js": {"modules/a.js", "modules/b.js"}
+type ScanSummary map[string][]string
+
+// ScanDirectories walks the given root directories and loads every file with a
+// supported extension into memory, skipping the AssetMin output files.
+// Once all files are loaded every asset is built a single time, so it can be used
+// to populate the bundles at startup instead of faking a "create" event per file.
+func (c *AssetMin) ScanDirectories(roots ...string) (ScanSummary, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	const e = "ScanDirectories "
+	summary := ScanSummary{}
+	var errs []error
+
+	for _, root := range roots {
+		err := c.walkSources(root, func(filePath, extension string) error {
+			content, err := os.ReadFile(filePath)
+			if err != nil {
+				return err
+			}
+			return c.loadFile(summary, filePath, extension, content)
+		})
+		if err != nil {
+			errs = append(errs, errors.New(e+root+" "+err.Error()))
+		}
+	}
+
+	if err := c.buildAll(); err != nil {
+		errs = append(errs, fmt.Errorf(e+"%w", err))
+	}
+
+	return summary, errors.Join(errs...)
+}
+
+// LoadFS loads every file with a supported extension from fsys (eg: an embed.FS)
+// into memory using the same extension routing as UpdateFileContentInMemory, then
+// builds every asset once. Nothing is read from disk; files are recorded with their
+// fsys path eg: "modules/app.js". Config.IgnorePatterns and an .assetminignore file
+// at the root of fsys are honoured, and the outputs are skipped when OutputDir is
+// relative to the root of fsys.
+func (c *AssetMin) LoadFS(fsys fs.FS) (ScanSummary, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	const e = "LoadFS "
+	summary := ScanSummary{}
+	var errs []error
+
+	ignoreFile, _ := fs.ReadFile(fsys, IgnoreFileName)
+	rules := parseIgnoreFile(ignoreFile)
+
+	err := fs.WalkDir(fsys, ".", func(filePath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if filePath != "." && c.isIgnoredFS(rules, filePath, true) {
+				return fs.SkipDir
+			}
+			return nil
+		}
+
+		extension := path.Ext(filePath)
+		// fsys paths are compared with the outputs as relative paths, eg: with
+		// os.DirFS(".") and OutputDir "web/public" the previous build is skipped
+		if c.assetFor(filePath, extension) == nil || c.isOutputPath(filepath.FromSlash(filePath)) || c.isIgnoredFS(rules, filePath, false) {
+			return nil
+		}
+
+		content, err := fs.ReadFile(fsys, filePath)
+		if err != nil {
+			return err
+		}
+		return c.loadFile(summary, filePath, extension, content)
+	})
+	if err != nil {
+		errs = append(errs, errors.New(e+err.Error()))
+	}
+
+	if err := c.buildAll(); err != nil {
+		errs = append(errs, fmt.Errorf(e+"%w", err))
+	}
+
+	return summary, errors.Join(errs...)
+}
+
+// loadFile stores a source file in its asset and records it in the summary.
+// Files the asset doesn't keep eg: complete HTML documents aren't recorded.
+func (c *AssetMin) loadFile(summary ScanSummary, filePath, extension string, content []byte) error {
+	fh, err := c.updateFileContent(filePath, extension, EventCreate, content)
+	if err != nil {
+		return err
+	}
+	if findFileIndex(fh.contentMiddle, filePath) == -1 {
+		return nil
+	}
+	summary[fh.fileOutputName] = append(summary[fh.fileOutputName], filePath)
+	return nil
+}
+
+// walkSources calls fn for every file under root that AssetMin can process
+// that is not excluded by the ignore rules
+func (c *AssetMin) walkSources(root string, fn func(filePath, extension string) error) error {
+	c.ignore.addRoot(root)
+
+	return filepath.WalkDir(root, func(filePath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if filePath != root && c.isIgnored(filePath, true) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		extension := filepath.Ext(filePath)
+		if c.assetFor(filePath, extension) == nil || c.isOutputPath(filePath) || c.isIgnored(filePath, false) {
+			return nil
+		}
+		return fn(filePath, extension)
+	})
+}
+
+// buildAll regenerates every asset that has something to serve, the quarantined
+// files are reported with the errors
+func (c *AssetMin) buildAll() error {
+	var errs []error
+	for _, fh := range c.assets() {
+		for _, f := range fh.contentMiddle {
+			if f.invalid != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", fh.fileOutputName, f.invalid))
+			}
+		}
+		if !fh.hasContentInMemory() && fh.initCode == nil {
+			continue
+		}
+		if err := c.processAsset(fh, ""); err != nil {
+			errs = append(errs, fmt.Errorf("%s %w", fh.fileOutputName, err))
+		}
+	}
+	return errors.Join(errs...)
+}
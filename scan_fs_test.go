@@ -0,0 +1,70 @@
+package assetmin
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestLoadFS verifies that bundles can be built from an fs.FS without touching
+// the disk, using the same extension routing as UpdateFileContentInMemory.
+func TestLoadFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		IgnoreFileName:                   {Data: []byte("drafts/\n")},
+		"modules/app/app.js":             {Data: []byte("'use strict';\nconsole.log('from fs');")},
+		"modules/app/app.css":            {Data: []byte(".app { color: red; }")},
+		"modules/app/app.test.js":        {Data: []byte("console.log('test');")},
+		"modules/drafts/wip.js":          {Data: []byte("console.log('draft');")},
+		"web/theme/favicon.svg":          {Data: []byte(`<svg xmlns="http://www.w3.org/2000/svg"><circle r="1"/></svg>`)},
+		"web/theme/icons/home.svg":       {Data: []byte(`<symbol id="icon-home"><path d="M0 0h1"/></symbol>`)},
+		"web/theme/components/card.html": {Data: []byte(`<div class="card">card</div>`)},
+		"web/theme/readme.md":            {Data: []byte("# not an asset")},
+	}
+
+	am := NewAssetMin(&Config{
+		OutputDir:               "unused",
+		IgnorePatterns:          []string{"*.test.js"},
+		GetRuntimeInitializerJS: func() (string, error) { return "", nil },
+	})
+
+	summary, err := am.LoadFS(fsys)
+	require.NoError(t, err)
+
+	require.Equal(t, ScanSummary{
+		"script.js":   {"modules/app/app.js"},
+		"style.css":   {"modules/app/app.css"},
+		"favicon.svg": {"web/theme/favicon.svg"},
+		"sprite.svg":  {"web/theme/icons/home.svg"},
+		"index.html":  {"web/theme/components/card.html"},
+	}, summary)
+
+	js, err := am.mainJsHandler.GetMinifiedContent(am.minifier())
+	require.NoError(t, err)
+	require.Equal(t, `"use strict";console.log("from fs")`, string(js))
+
+	css, err := am.mainStyleCssHandler.GetMinifiedContent(am.minifier())
+	require.NoError(t, err)
+	require.Equal(t, ".app{color:red}", string(css))
+
+	html, err := am.indexHtmlHandler.GetMinifiedContent(am.minifier())
+	require.NoError(t, err)
+	require.Contains(t, string(html), `<div class="card">card</div>`)
+
+	t.Run("skips_outputs_inside_fs", func(t *testing.T) {
+		am := NewAssetMin(&Config{
+			OutputDir:               "web/public",
+			OutputFS:                NewMemFS(),
+			GetRuntimeInitializerJS: func() (string, error) { return "", nil },
+		})
+		am.SetWorkMode(DiskMode)
+
+		summary, err := am.LoadFS(fstest.MapFS{
+			"modules/app.js":       {Data: []byte("console.log('source');")},
+			"web/public/script.js": {Data: []byte("console.log('previous build');")},
+			"web/public/style.css": {Data: []byte(".previous{color:red}")},
+		})
+		require.NoError(t, err)
+		require.Equal(t, ScanSummary{"script.js": {"modules/app.js"}}, summary)
+	})
+}
@@ -0,0 +1,70 @@
+package assetmin
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestScanDirectories verifies that an initial scan loads every supported file
+// into its bundle, skips the output files and builds each asset once.
+func TestScanDirectories(t *testing.T) {
+	env := setupTestEnv("scan_directories", t)
+	env.AssetsHandler.SetWorkMode(DiskMode)
+	defer env.CleanDirectory()
+
+	files := map[string]string{
+		filepath.Join(env.ModulesDir, "module1", "app.js"):    "console.log('Module One');",
+		filepath.Join(env.ModulesDir, "module2", "utils.js"):  "console.log('Module Two');",
+		filepath.Join(env.ModulesDir, "module1", "style.css"): ".module-one { color: red; }",
+		filepath.Join(env.ThemeDir, "icons", "home.svg"):      `<symbol id="icon-home"><path d="M0 0h1"/></symbol>`,
+		filepath.Join(env.ThemeDir, "readme.txt"):             "not an asset",
+		filepath.Join(env.ModulesDir, "module1", "card.html"): "<div>card</div>",
+		// complete documents are templates, not stored as modules
+		filepath.Join(env.ThemeDir, "layout.html"): "<!DOCTYPE html><html><body></body></html>",
+		// stale output from a previous run must not be ingested
+		env.MainJsPath: "console.log('stale output');",
+	}
+	for path, content := range files {
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+
+	// absolute roots while OutputDir stays relative, eg: assetmin build -src $PWD
+	abs := func(path string) string {
+		p, err := filepath.Abs(path)
+		require.NoError(t, err)
+		return p
+	}
+	modules, theme := abs(env.ModulesDir), abs(env.ThemeDir)
+	summary, err := env.AssetsHandler.ScanDirectories(modules, theme, abs(env.PublicDir))
+	require.NoError(t, err)
+
+	require.ElementsMatch(t, []string{
+		filepath.Join(modules, "module1", "app.js"),
+		filepath.Join(modules, "module2", "utils.js"),
+	}, summary["script.js"])
+	require.Equal(t, []string{filepath.Join(modules, "module1", "style.css")}, summary["style.css"])
+	require.Equal(t, []string{filepath.Join(theme, "icons", "home.svg")}, summary["sprite.svg"])
+	require.Equal(t, []string{filepath.Join(modules, "module1", "card.html")}, summary["index.html"])
+
+	js, err := os.ReadFile(env.MainJsPath)
+	require.NoError(t, err)
+	require.Contains(t, string(js), "Module One")
+	require.Contains(t, string(js), "Module Two")
+	require.NotContains(t, string(js), "stale output")
+
+	css, err := os.ReadFile(env.MainCssPath)
+	require.NoError(t, err)
+	require.Contains(t, string(css), ".module-one{color:red}")
+
+	require.FileExists(t, env.MainSvgPath)
+	require.FileExists(t, env.MainHtmlPath)
+
+	t.Run("missing_root_is_reported", func(t *testing.T) {
+		_, err := env.AssetsHandler.ScanDirectories(filepath.Join(env.BaseDir, "missing"))
+		require.Error(t, err)
+	})
+}
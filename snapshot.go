@@ -0,0 +1,165 @@
+package assetmin
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+// snapshotVersion changes when the snapshot format isn't compatible anymore
+const snapshotVersion = 1
+
+// snapshot is the persisted in-memory state used for warm starts
+type snapshot struct {
+	Version  int                      `json:"version"`
+	Minifier string                   `json:"minifier"` // options the minified caches were built with
+	Roots    []string                 `json:"roots"`    // source roots whose ignore files apply to the files
+	Assets   map[string]snapshotAsset `json:"assets"`   // by output name eg: script.js
+}
+
+type snapshotAsset struct {
+	Files        []snapshotFile `json:"files"`        // source files in bundle order
+	SourceHash   string         `json:"sourceHash"`   // hash of the content Minified was produced from
+	Transformers string         `json:"transformers"` // transformers Minified was produced with, see transformersKey
+	Minified     []byte         `json:"minified"`
+}
+
+type snapshotFile struct {
+	Path string `json:"path"`
+	Hash string `json:"hash"` // hash of the content stored in memory
+}
+
+// SnapshotResult reports what LoadSnapshot could reuse
+type SnapshotResult struct {
+	Reused  []string // source files unchanged since the snapshot
+	Stale   []string // source files changed, missing or ignored since the snapshot
+	Rebuilt []string // assets minified again because their content changed
+}
+
+// SaveSnapshot writes the source file list, content hashes and minified caches of
+// every asset to filePath through Config.OutputFS, so a restart can skip
+// minifying unchanged bundles with LoadSnapshot.
+func (c *AssetMin) SaveSnapshot(filePath string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	const e = "SaveSnapshot "
+	snap := snapshot{Version: snapshotVersion, Minifier: c.minifierKey(), Roots: c.ignore.rootList(), Assets: map[string]snapshotAsset{}}
+
+	for _, fh := range c.assets() {
+		if _, err := fh.GetMinifiedContent(c.minifier()); err != nil {
+			// a broken bundle has nothing worth saving
+			continue
+		}
+
+		fh.mu.RLock()
+		sa := snapshotAsset{SourceHash: fh.sourceHash, Transformers: fh.transformersKey(), Minified: fh.cachedMinified}
+		fh.mu.RUnlock()
+
+		for _, f := range fh.contentMiddle {
+			sa.Files = append(sa.Files, snapshotFile{Path: f.path, Hash: f.hash()})
+		}
+		snap.Assets[fh.fileOutputName] = sa
+	}
+
+	data, err := json.Marshal(snap)
+	if err != nil {
+		return errors.New(e + err.Error())
+	}
+	if err := c.OutputFS.WriteFile(filePath, data, c.FileMode); err != nil {
+		return errors.New(e + err.Error())
+	}
+	return nil
+}
+
+// LoadSnapshot restores the state saved by SaveSnapshot. Every recorded source
+// file is read again from disk in its original order and validated against its
+// hash. Files that the ignore rules exclude now, or that are outputs, are dropped
+// as stale; the ignore files of the recorded source roots are read again for
+// that. Assets whose content, minifier options and transformers are unchanged
+// get their minified cache back without minifying, the others are rebuilt.
+// Files added since the snapshot are not known here, run ScanDirectories
+// afterwards to pick them up.
+func (c *AssetMin) LoadSnapshot(filePath string) (*SnapshotResult, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	const e = "LoadSnapshot "
+	data, err := c.OutputFS.ReadFile(filePath)
+	if err != nil {
+		return nil, errors.New(e + err.Error())
+	}
+
+	var snap snapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return nil, errors.New(e + err.Error())
+	}
+	if snap.Version != snapshotVersion {
+		return nil, errors.New(e + "unsupported snapshot version")
+	}
+
+	for _, root := range snap.Roots {
+		c.ignore.addRoot(root)
+	}
+
+	result := &SnapshotResult{}
+	var errs []error
+
+	for _, fh := range c.assets() {
+		sa, ok := snap.Assets[fh.fileOutputName]
+		if !ok {
+			continue
+		}
+
+		for _, sf := range sa.Files {
+			if c.isOutputPath(sf.Path) || c.isIgnored(sf.Path, false) {
+				// excluded since the snapshot was saved, same filters as walkSources
+				result.Stale = append(result.Stale, sf.Path)
+				continue
+			}
+			content, err := os.ReadFile(sf.Path)
+			if err != nil {
+				result.Stale = append(result.Stale, sf.Path)
+				continue
+			}
+			if _, err := c.updateFileContent(sf.Path, filepath.Ext(sf.Path), EventCreate, content); err != nil {
+				errs = append(errs, errors.New(sf.Path+" "+err.Error()))
+				continue
+			}
+
+			if idx := findFileIndex(fh.contentMiddle, sf.Path); idx != -1 && fh.contentMiddle[idx].hash() == sf.Hash {
+				result.Reused = append(result.Reused, sf.Path)
+			} else {
+				result.Stale = append(result.Stale, sf.Path)
+			}
+		}
+
+		fh.mu.Lock()
+		if snap.Minifier == c.minifierKey() && sa.Transformers == fh.transformersKey() {
+			// caches built with other minifier options or transformers are not reused
+			fh.sourceHash = sa.SourceHash
+			fh.setOutput(sa.Minified)
+		}
+		before := fh.sourceHash
+		err := fh.rebuild(c.minifier())
+		rebuilt := fh.sourceHash != before
+		fh.mu.Unlock()
+
+		if err != nil {
+			errs = append(errs, errors.New(fh.fileOutputName+" "+err.Error()))
+			continue
+		}
+		if rebuilt {
+			result.Rebuilt = append(result.Rebuilt, fh.fileOutputName)
+		}
+		if err := c.processAsset(fh, ""); err != nil {
+			errs = append(errs, errors.New(fh.fileOutputName+" "+err.Error()))
+		}
+	}
+
+	if len(errs) > 0 {
+		return result, errors.New(e + errors.Join(errs...).Error())
+	}
+	return result, nil
+}
@@ -0,0 +1,147 @@
+package assetmin
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestSnapshot verifies that a saved snapshot restores the bundles on a new
+// instance, reusing the minified cache of unchanged assets and rebuilding only
+// the ones whose source files changed.
+func TestSnapshot(t *testing.T) {
+	dir := t.TempDir()
+	jsPath := filepath.Join(dir, "modules", "app.js")
+	jsPath2 := filepath.Join(dir, "modules", "utils.js")
+	cssPath := filepath.Join(dir, "modules", "app.css")
+	for path, content := range map[string]string{
+		jsPath:  "console.log('app');",
+		jsPath2: "console.log('utils');",
+		cssPath: ".app { color: red; }",
+	} {
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+
+	newAssetMin := func() *AssetMin {
+		return NewAssetMin(&Config{
+			OutputDir:               filepath.Join(dir, "public"),
+			GetRuntimeInitializerJS: func() (string, error) { return "", nil },
+		})
+	}
+	snapshotPath := filepath.Join(dir, "cache", "assetmin.snapshot")
+
+	first := newAssetMin()
+	_, err := first.ScanDirectories(filepath.Join(dir, "modules"))
+	require.NoError(t, err)
+	require.NoError(t, first.SaveSnapshot(snapshotPath))
+
+	// change one source file and remove another while "stopped"
+	require.NoError(t, os.WriteFile(cssPath, []byte(".app { color: blue; }"), 0644))
+	require.NoError(t, os.Remove(jsPath2))
+
+	second := newAssetMin()
+	result, err := second.LoadSnapshot(snapshotPath)
+	require.NoError(t, err)
+	require.Equal(t, []string{jsPath}, result.Reused)
+	require.ElementsMatch(t, []string{cssPath, jsPath2}, result.Stale)
+	require.ElementsMatch(t, []string{"script.js", "style.css"}, result.Rebuilt)
+
+	css, err := second.mainStyleCssHandler.GetMinifiedContent(second.minifier())
+	require.NoError(t, err)
+	require.Equal(t, ".app{color:blue}", string(css))
+
+	js, err := second.mainJsHandler.GetMinifiedContent(second.minifier())
+	require.NoError(t, err)
+	require.Contains(t, string(js), "app")
+	require.NotContains(t, string(js), "utils")
+
+	t.Run("unchanged_sources_skip_minification", func(t *testing.T) {
+		require.NoError(t, second.SaveSnapshot(snapshotPath))
+
+		third := newAssetMin()
+		result, err := third.LoadSnapshot(snapshotPath)
+		require.NoError(t, err)
+		require.Empty(t, result.Stale)
+		require.Empty(t, result.Rebuilt)
+
+		// a following scan finds nothing new and keeps the restored caches
+		third.mainJsHandler.cachedMinified = []byte("restored")
+		_, err = third.ScanDirectories(filepath.Join(dir, "modules"))
+		require.NoError(t, err)
+		js, err := third.mainJsHandler.GetMinifiedContent(third.minifier())
+		require.NoError(t, err)
+		require.Equal(t, "restored", string(js))
+	})
+
+	t.Run("other_transformers_rebuild", func(t *testing.T) {
+		banner := func(text string) Transformer {
+			return TransformerFuncs{Version: text, After: func(_ string, min []byte) ([]byte, error) {
+				return append([]byte(text), min...), nil
+			}}
+		}
+		withBanner := func(text string) *AssetMin {
+			am := newAssetMin()
+			require.NoError(t, am.RegisterTransformer("style.css", banner(text)))
+			return am
+		}
+
+		v1 := withBanner("/*v1*/")
+		_, err := v1.ScanDirectories(filepath.Join(dir, "modules"))
+		require.NoError(t, err)
+		require.NoError(t, v1.SaveSnapshot(snapshotPath))
+
+		same := withBanner("/*v1*/")
+		result, err := same.LoadSnapshot(snapshotPath)
+		require.NoError(t, err)
+		require.Empty(t, result.Rebuilt)
+
+		// only AfterMinify changed, the sources hash the same
+		v2 := withBanner("/*v2*/")
+		result, err = v2.LoadSnapshot(snapshotPath)
+		require.NoError(t, err)
+		require.Equal(t, []string{"style.css"}, result.Rebuilt)
+		css, err := v2.mainStyleCssHandler.GetMinifiedContent(v2.minifier())
+		require.NoError(t, err)
+		require.Equal(t, "/*v2*/.app{color:blue}", string(css))
+	})
+
+	t.Run("ignored_since_snapshot_are_dropped", func(t *testing.T) {
+		modules := filepath.Join(dir, "modules")
+		saved := newAssetMin()
+		_, err := saved.ScanDirectories(modules)
+		require.NoError(t, err)
+		require.NoError(t, saved.SaveSnapshot(snapshotPath))
+
+		// excluded by the root ignore file once "stopped"
+		require.NoError(t, os.WriteFile(filepath.Join(modules, IgnoreFileName), []byte("app.css\n"), 0644))
+
+		restored := newAssetMin()
+		result, err := restored.LoadSnapshot(snapshotPath)
+		require.NoError(t, err)
+		require.Equal(t, []string{jsPath}, result.Reused)
+		require.Equal(t, []string{cssPath}, result.Stale)
+		require.Empty(t, restored.mainStyleCssHandler.contentMiddle)
+
+		// and by Config.IgnorePatterns
+		require.NoError(t, os.Remove(filepath.Join(modules, IgnoreFileName)))
+		byConfig := NewAssetMin(&Config{
+			OutputDir:               filepath.Join(dir, "public"),
+			IgnorePatterns:          []string{"app.js"},
+			GetRuntimeInitializerJS: func() (string, error) { return "", nil },
+		})
+		result, err = byConfig.LoadSnapshot(snapshotPath)
+		require.NoError(t, err)
+		require.Equal(t, []string{jsPath}, result.Stale)
+		js, err := byConfig.mainJsHandler.GetMinifiedContent(byConfig.minifier())
+		require.NoError(t, err)
+		require.NotContains(t, string(js), "app")
+	})
+
+	t.Run("missing_snapshot", func(t *testing.T) {
+		_, err := newAssetMin().LoadSnapshot(filepath.Join(dir, "missing"))
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,75 @@
+package assetmin
+
+import (
+	"sync"
+	"time"
+)
+
+// defaultSubscriberBuffer is the channel capacity used when Subscribe gets 0
+const defaultSubscriberBuffer = 16
+
+// AssetEvent describes the result of one rebuild of an asset
+type AssetEvent struct {
+	Asset    string        // output name eg: script.js
+	URL      string        // HTTP route eg: /assets/script.js
+	OldHash  string        // content hash before the rebuild, empty for the first build
+	NewHash  string        // content hash after the rebuild, empty when it failed
+	OldSize  int           // bytes before the rebuild
+	NewSize  int           // bytes after the rebuild
+	Duration time.Duration // time spent rebuilding and writing
+	File     string        // source file that triggered the rebuild, empty for scans and refreshes
+	Err      error         // rebuild or write error
+}
+
+// Changed reports whether the served content is different after the rebuild
+func (e AssetEvent) Changed() bool {
+	return e.Err == nil && e.OldHash != e.NewHash
+}
+
+// subscribers fans out AssetEvents without ever blocking the rebuild path
+type subscribers struct {
+	mu   sync.Mutex
+	next int
+	chs  map[int]chan AssetEvent
+}
+
+// Subscribe returns a channel receiving an AssetEvent after every rebuild and a
+// function that stops the subscription and closes the channel. Events are sent
+// without blocking: when the buffer (default 16) is full the event is dropped
+// for that subscriber, so read the channel promptly or use a larger buffer.
+func (c *AssetMin) Subscribe(buffer int) (<-chan AssetEvent, func()) {
+	if buffer <= 0 {
+		buffer = defaultSubscriberBuffer
+	}
+	ch := make(chan AssetEvent, buffer)
+
+	c.subs.mu.Lock()
+	if c.subs.chs == nil {
+		c.subs.chs = map[int]chan AssetEvent{}
+	}
+	id := c.subs.next
+	c.subs.next++
+	c.subs.chs[id] = ch
+	c.subs.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			c.subs.mu.Lock()
+			delete(c.subs.chs, id)
+			c.subs.mu.Unlock()
+			close(ch)
+		})
+	}
+}
+
+func (s *subscribers) publish(ev AssetEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, ch := range s.chs {
+		select {
+		case ch <- ev:
+		default: // slow subscriber, drop instead of blocking the rebuild
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package assetmin
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestSubscribe verifies that every rebuild emits a structured AssetEvent and that
+// a full subscriber never blocks the rebuild path.
+func TestSubscribe(t *testing.T) {
+	env := setupTestEnv("subscribe", t)
+	env.AssetsHandler.SetWorkMode(DiskMode)
+	defer env.CleanDirectory()
+
+	events, unsubscribe := env.AssetsHandler.Subscribe(4)
+
+	path := filepath.Join(env.ModulesDir, "sub", "app.css")
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, os.WriteFile(path, []byte(".app { color: red; }"), 0644))
+	require.NoError(t, env.AssetsHandler.NewFileEventKind("app.css", ".css", path, EventCreate))
+
+	ev := <-events
+	require.Equal(t, "style.css", ev.Asset)
+	require.Equal(t, "/style.css", ev.URL)
+	require.Equal(t, path, ev.File)
+	require.NoError(t, ev.Err)
+	require.Empty(t, ev.OldHash)
+	require.Equal(t, contentHash([]byte(".app{color:red}")), ev.NewHash)
+	require.Equal(t, len(".app{color:red}"), ev.NewSize)
+	require.True(t, ev.Changed())
+
+	// a write with the same content rebuilds without changing the output
+	require.NoError(t, env.AssetsHandler.NewFileEventKind("app.css", ".css", path, EventWrite))
+	ev = <-events
+	require.Equal(t, ev.OldHash, ev.NewHash)
+	require.False(t, ev.Changed())
+
+	t.Run("error_is_reported", func(t *testing.T) {
+		// valid on its own, it breaks the bundle declaring dup again
+		_, err := env.AssetsHandler.UpdateFileContentInMemory(filepath.Join(env.ModulesDir, "sub", "first.js"), ".js", "create", []byte("let dup = 1;"))
+		require.NoError(t, err)
+		broken := filepath.Join(env.ModulesDir, "sub", "broken.js")
+		require.NoError(t, os.WriteFile(broken, []byte("let dup = 2;"), 0644))
+		require.Error(t, env.AssetsHandler.NewFileEventKind("broken.js", ".js", broken, EventCreate))
+
+		ev := <-events
+		require.Equal(t, "script.js", ev.Asset)
+		require.Error(t, ev.Err)
+		require.Empty(t, ev.NewHash)
+		require.False(t, ev.Changed())
+
+		require.NoError(t, env.AssetsHandler.NewFileEventKind("broken.js", ".js", broken, EventRemove))
+		<-events
+	})
+
+	t.Run("full_buffer_does_not_block", func(t *testing.T) {
+		for range 10 {
+			env.AssetsHandler.RefreshAsset(".css")
+		}
+		require.Len(t, events, 4)
+	})
+
+	unsubscribe()
+	unsubscribe()
+	for range events {
+	}
+	env.AssetsHandler.RefreshAsset(".css") // no subscriber left, nothing to deliver
+}
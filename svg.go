@@ -1,5 +1,15 @@
 package assetmin
 
+import (
+	"bytes"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/tdewolff/parse/v2"
+	"github.com/tdewolff/parse/v2/xml"
+)
+
 func NewSvgHandler(ac *Config, outputName string) *asset {
 	svgh := newAssetFile(outputName, "image/svg+xml", ac, nil)
 
@@ -17,9 +27,127 @@ func NewSvgHandler(ac *Config, outputName string) *asset {
 	</svg>`),
 	})
 
+	// exported icons are standalone <svg> files, they must be symbols to be used with <use href>
+	svgh.addTransformer(svgSymbolTransformer)
+
 	return svgh
 }
 
+// symbolTag matches a <symbol> element, not <symbolic> or the like
+var symbolTag = regexp.MustCompile(`<symbol[\s/>]`)
+
+// svgSymbolTransformer turns the standalone icons of a sprite into symbols
+var svgSymbolTransformer = TransformerFuncs{File: svgToSymbol}
+
+// svgToSymbol converts an icon whose root element is <svg> into a <symbol>. The
+// id is the one of the root or the file name eg: icons/arrow-left.svg -> "arrow-left".
+// viewBox and the other attributes are kept, xmlns, width and height are dropped.
+// Files that already are symbols, or that can't be read as svg, are left as they are.
+func svgToSymbol(filePath string, content []byte) ([]byte, error) {
+	l := xml.NewLexer(parse.NewInputBytes(content))
+	// the lexer drops the whitespace before ">", "/>" and "?>", so the position
+	// of each token is searched from the end of the previous one
+	offset := 0
+	advance := func(data []byte) {
+		if i := bytes.Index(content[offset:], data); i > 0 {
+			offset += i
+		}
+		offset += len(data)
+	}
+
+	// skip the prolog and comments up to the root element
+	for {
+		tt, data := l.Next()
+		if tt == xml.ErrorToken {
+			return content, nil
+		}
+		advance(data)
+		if tt == xml.StartTagToken {
+			if string(l.Text()) != "svg" {
+				return content, nil // eg: already a <symbol>
+			}
+			break
+		}
+	}
+
+	id := ""
+	var attrs []byte
+	var inner []byte
+	for done := false; !done; {
+		tt, data := l.Next()
+		if tt != xml.ErrorToken {
+			advance(data)
+		}
+		switch tt {
+		case xml.AttributeToken:
+			switch name := string(l.Text()); name {
+			case "id":
+				id = strings.Trim(string(l.AttrVal()), `"'`)
+			case "xmlns", "width", "height":
+			default:
+				attrs = append(attrs, data...)
+			}
+		case xml.StartTagCloseToken:
+			// the children end at the end tag of the root, found by depth so a
+			// "</svg>" in a trailing comment or text isn't taken for it
+			start, end := offset, -1
+			for depth := 1; depth > 0; {
+				tt, data := l.Next()
+				switch tt {
+				case xml.ErrorToken:
+					return content, nil // unclosed root
+				case xml.StartTagToken:
+					depth++
+				case xml.StartTagCloseVoidToken, xml.EndTagToken:
+					depth--
+				}
+				if i := bytes.Index(content[offset:], data); depth == 0 && i >= 0 {
+					end = offset + i
+				}
+				advance(data)
+			}
+			if end < 0 {
+				return content, nil
+			}
+			inner = content[start:end]
+			done = true
+		case xml.StartTagCloseVoidToken:
+			done = true
+		default:
+			return content, nil
+		}
+	}
+
+	if symbolTag.Match(inner) {
+		// a sprite of its own, its symbols are used as they are
+		return bytes.TrimSpace(inner), nil
+	}
+
+	if id == "" {
+		id = symbolID(filePath)
+	}
+	var out bytes.Buffer
+	out.WriteString(`<symbol id="` + strings.ReplaceAll(id, `"`, "&quot;") + `"`)
+	out.Write(attrs)
+	out.WriteString(">")
+	out.Write(bytes.TrimSpace(inner))
+	out.WriteString("</symbol>")
+	return out.Bytes(), nil
+}
+
+// symbolID returns the file name without extension, with the characters not
+// allowed in an id replaced by "-"
+func symbolID(filePath string) string {
+	name := strings.TrimSuffix(filepath.Base(filePath), filepath.Ext(filePath))
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+			return r
+		}
+		return '-'
+	}, name)
+}
+
 // NewFaviconSvgHandler creates a handler for favicon.svg that simply minifies and copies the file
 // without sprite wrapping. This handler processes standalone SVG files like favicon.svg
 func NewFaviconSvgHandler(ac *Config, outputName string) *asset {
@@ -0,0 +1,117 @@
+package assetmin
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSvgToSymbol(t *testing.T) {
+	cases := []struct {
+		name, path, in, want string
+	}{
+		{
+			name: "exported icon",
+			path: "icons/arrow-left.svg",
+			in:   `<?xml version="1.0" encoding="UTF-8"?>` + "\n<!-- exported -->\n" + `<svg xmlns="http://www.w3.org/2000/svg" width="24" height="24" viewBox="0 0 24 24" fill="none"><path d="M0 0"/></svg>` + "\n",
+			want: `<symbol id="arrow-left" viewBox="0 0 24 24" fill="none"><path d="M0 0"/></symbol>`,
+		},
+		{
+			name: "root id is kept",
+			path: "icons/home.svg",
+			in:   `<svg id="icon-home" viewBox='0 0 16 16' xmlns:xlink="http://www.w3.org/1999/xlink"><use xlink:href="#a"/></svg>`,
+			want: `<symbol id="icon-home" viewBox='0 0 16 16' xmlns:xlink="http://www.w3.org/1999/xlink"><use xlink:href="#a"/></symbol>`,
+		},
+		{
+			name: "file name is sanitized",
+			path: "icons/my icon.svg",
+			in:   `<svg viewBox="0 0 1 1"/>`,
+			want: `<symbol id="my-icon" viewBox="0 0 1 1"></symbol>`,
+		},
+		{
+			name: "multi-line root tag",
+			path: "icons/a b.svg",
+			in:   "<svg\n xmlns=\"x\"\n viewBox=\"0 0 1 1\"\n><path/></svg>",
+			want: "<symbol id=\"a-b\"\n viewBox=\"0 0 1 1\"><path/></symbol>",
+		},
+		{
+			name: "space before the end of the root and the prolog",
+			path: "icons/dot.svg",
+			in:   `<?xml version="1.0" ?><svg viewBox="0 0 2 2" ><circle r="1" /></svg>`,
+			want: `<symbol id="dot" viewBox="0 0 2 2"><circle r="1" /></symbol>`,
+		},
+		{
+			name: "symbolic element isn't a symbol",
+			path: "icons/odd.svg",
+			in:   `<svg viewBox="0 0 1 1"><symbolic/></svg>`,
+			want: `<symbol id="odd" viewBox="0 0 1 1"><symbolic/></symbol>`,
+		},
+		{
+			name: "symbol is left as is",
+			path: "icons/star.svg",
+			in:   `<symbol id="icon-star"><path d="M1 1"/></symbol>`,
+			want: `<symbol id="icon-star"><path d="M1 1"/></symbol>`,
+		},
+		{
+			name: "sprite file is unwrapped",
+			path: "icons/set.svg",
+			in:   `<svg xmlns="http://www.w3.org/2000/svg"><symbol id="a"/><symbol id="b"/></svg>`,
+			want: `<symbol id="a"/><symbol id="b"/>`,
+		},
+		{
+			name: "end tag in a trailing comment",
+			path: "icons/styled.svg",
+			in:   `<svg viewBox="0 0 1 1"><style>.a{}</style></svg><!-- trailing </svg> -->`,
+			want: `<symbol id="styled" viewBox="0 0 1 1"><style>.a{}</style></symbol>`,
+		},
+		{
+			name: "nested svg",
+			path: "icons/nested.svg",
+			in:   `<svg viewBox="0 0 2 2"><svg x="1"><path/></svg></svg >`,
+			want: `<symbol id="nested" viewBox="0 0 2 2"><svg x="1"><path/></svg></symbol>`,
+		},
+		{
+			name: "unclosed svg is left as is",
+			path: "icons/broken.svg",
+			in:   `<svg viewBox="0 0 1 1"><path d="M0 0"/>`,
+			want: `<svg viewBox="0 0 1 1"><path d="M0 0"/>`,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			out, err := svgToSymbol(tc.path, []byte(tc.in))
+			require.NoError(t, err)
+			require.Equal(t, tc.want, string(out))
+		})
+	}
+}
+
+// TestSpriteSymbols verifies that standalone icons dropped in a folder can be
+// referenced from the sprite, while the favicon keeps its svg root.
+func TestSpriteSymbols(t *testing.T) {
+	env := setupTestEnv("sprite_symbols", t)
+	defer env.CleanDirectory()
+	am := env.AssetsHandler
+
+	iconsDir := filepath.Join(env.ThemeDir, "icons")
+	require.NoError(t, os.MkdirAll(iconsDir, 0755))
+	icon := filepath.Join(iconsDir, "close.svg")
+	require.NoError(t, os.WriteFile(icon, []byte(`<svg xmlns="http://www.w3.org/2000/svg" width="24" height="24" viewBox="0 0 24 24"><path d="M6 6l12 12"/></svg>`), 0644))
+	favicon := filepath.Join(env.ThemeDir, "favicon.svg")
+	require.NoError(t, os.WriteFile(favicon, []byte(`<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 8 8"><circle r="1"/></svg>`), 0644))
+
+	_, err := am.ScanDirectories(env.ThemeDir)
+	require.NoError(t, err)
+
+	sprite, err := am.spriteSvgHandler.GetMinifiedContent(am.minifier())
+	require.NoError(t, err)
+	require.Contains(t, string(sprite), `<defs><symbol id="close" viewBox="0 0 24 24"><path d="M6 6l12 12"/></symbol></defs>`)
+	require.NotContains(t, string(sprite), `width="24"`)
+
+	icon16, err := am.faviconSvgHandler.GetMinifiedContent(am.minifier())
+	require.NoError(t, err)
+	require.Contains(t, string(icon16), "<svg")
+	require.NotContains(t, string(icon16), "<symbol")
+}
@@ -95,7 +95,7 @@ func TestSvgSpriteStructure(t *testing.T) {
 			content: []byte(iconContent),
 		}
 		// Add the icon to the handler without writing to disk
-		require.NoError(t, svgHandler.UpdateContent(iconFile.path, "create", iconFile))
+		require.NoError(t, svgHandler.UpdateContent(iconFile.path, EventCreate, iconFile))
 
 		// En lugar de escribir en disco y leer el archivo, verificamos directamente
 		// el contenido en memoria combinando contentOpen + contenido del símbolo + contentClose
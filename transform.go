@@ -0,0 +1,217 @@
+package assetmin
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+)
+
+// Transformer adds processing steps to the build of an asset. Each hook gets the
+// content and returns the replacement, an error stops the build of the asset.
+type Transformer interface {
+	// TransformFile runs on every source file before it's concatenated into the bundle
+	TransformFile(filePath string, content []byte) ([]byte, error)
+	// BeforeMinify runs on the whole bundle, wrappers and init code included
+	BeforeMinify(asset string, bundle []byte) ([]byte, error)
+	// AfterMinify runs on the minified output
+	AfterMinify(asset string, minified []byte) ([]byte, error)
+}
+
+// Fingerprinter is implemented by transformers whose output depends on more
+// than their type eg: a banner text. Fingerprint changes when the output does,
+// so a snapshot made with other hooks isn't reused, see LoadSnapshot.
+type Fingerprinter interface {
+	Fingerprint() string
+}
+
+// TransformerFuncs implements Transformer with optional functions, nil hooks
+// leave the content unchanged eg: TransformerFuncs{After: addBanner}
+type TransformerFuncs struct {
+	File    func(filePath string, content []byte) ([]byte, error)
+	Before  func(asset string, bundle []byte) ([]byte, error)
+	After   func(asset string, minified []byte) ([]byte, error)
+	Version string // returned by Fingerprint, change it when the hooks produce another output
+}
+
+func (t TransformerFuncs) Fingerprint() string {
+	return t.Version
+}
+
+func (t TransformerFuncs) TransformFile(filePath string, content []byte) ([]byte, error) {
+	if t.File == nil {
+		return content, nil
+	}
+	return t.File(filePath, content)
+}
+
+func (t TransformerFuncs) BeforeMinify(asset string, bundle []byte) ([]byte, error) {
+	if t.Before == nil {
+		return bundle, nil
+	}
+	return t.Before(asset, bundle)
+}
+
+func (t TransformerFuncs) AfterMinify(asset string, minified []byte) ([]byte, error) {
+	if t.After == nil {
+		return minified, nil
+	}
+	return t.After(asset, minified)
+}
+
+// RegisterTransformer adds t to the assets matching key, after the transformers
+// already registered. key is a source extension (".js"), a media type
+// ("text/css") or an output name ("script.js"). The matching assets are rebuilt
+// on their next event or request, so register transformers before loading files.
+func (c *AssetMin) RegisterTransformer(key string, t Transformer) error {
+	const e = "RegisterTransformer "
+	if t == nil {
+		return errors.New(e + "transformer is nil")
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	targets := c.assetsForKey(key)
+	if len(targets) == 0 {
+		return errors.New(e + "no asset matches " + key)
+	}
+	for _, fh := range targets {
+		fh.addTransformer(t)
+	}
+	return nil
+}
+
+// assetsForKey returns the assets matching an extension, media type or output name
+func (c *AssetMin) assetsForKey(key string) []*asset {
+	var out []*asset
+	for _, fh := range c.assets() {
+		switch {
+		case key == fh.fileOutputName, key == fh.mediatype:
+		case strings.HasPrefix(key, ".") && key == path.Ext(fh.fileOutputName):
+		default:
+			continue
+		}
+		out = append(out, fh)
+	}
+	return out
+}
+
+func (h *asset) addTransformer(t Transformer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.transformers = append(h.transformers, t)
+	h.cacheValid = false
+	h.sourceHash = "" // the same sources now produce another output
+	for _, f := range h.contentMiddle {
+		f.facts = nil // computed from the transformed content
+	}
+}
+
+// transformersKey identifies the transformers of h in order, by type and
+// Fingerprint, empty when there are none. The caller must hold the lock.
+func (h *asset) transformersKey() string {
+	if len(h.transformers) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	for _, t := range h.transformers {
+		fmt.Fprintf(&b, "%T", t)
+		if f, ok := t.(Fingerprinter); ok {
+			b.WriteString(" " + f.Fingerprint())
+		}
+		b.WriteString("\n")
+	}
+	return contentHash([]byte(b.String()))
+}
+
+// transformFile returns the content of a source file with the TransformFile hooks applied
+func (h *asset) transformFile(f *contentFile) ([]byte, error) {
+	content := f.content
+	for _, t := range h.transformers {
+		var err error
+		if content, err = t.TransformFile(f.path, content); err != nil {
+			return nil, errors.New(f.path + " " + err.Error())
+		}
+	}
+	return content, nil
+}
+
+// bundle returns the content to minify: init code, wrappers and source files
+// with the TransformFile and BeforeMinify hooks applied
+func (h *asset) bundle() ([]byte, error) {
+	out, _, err := h.bundleSegments()
+	return out, err
+}
+
+// bundleSegments returns the bundle with the position of every file in it. The
+// positions are those before BeforeMinify, a hook that adds or removes lines
+// shifts the diagnostics of the files after the change.
+func (h *asset) bundleSegments() ([]byte, []segment, error) {
+	var buf bytes.Buffer
+	pos := newSegmentWriter()
+	write := func(path string, content []byte) {
+		pos.add(path, content)
+		buf.Write(content)
+		buf.WriteString("\n")
+		pos.advance([]byte("\n"))
+	}
+
+	if h.initCode != nil {
+		if initCode, err := h.initCode(); err == nil {
+			pos.add("(init code)", []byte(initCode))
+			buf.WriteString(initCode)
+		}
+	}
+	for _, f := range h.contentOpen {
+		write(f.path, f.content)
+	}
+	written := 0
+	for _, f := range h.contentMiddle {
+		if f.invalid != nil {
+			continue // quarantined, see validateSource
+		}
+		content, err := h.transformFile(f)
+		if err != nil {
+			return nil, nil, err
+		}
+		if written > 0 && h.separator != "" {
+			buf.WriteString(h.separator)
+			pos.advance([]byte(h.separator))
+		}
+		write(f.path, content)
+		written++
+	}
+	for _, f := range h.contentClose {
+		write(f.path, f.content)
+	}
+
+	out := buf.Bytes()
+	for _, t := range h.transformers {
+		var err error
+		if out, err = t.BeforeMinify(h.fileOutputName, out); err != nil {
+			return nil, nil, err
+		}
+	}
+	return out, pos.segments, nil
+}
+
+// afterMinify applies the AfterMinify hooks to the minified output
+func (h *asset) afterMinify(minified []byte) ([]byte, error) {
+	for _, t := range h.transformers {
+		var err error
+		if minified, err = t.AfterMinify(h.fileOutputName, minified); err != nil {
+			return nil, err
+		}
+	}
+	return minified, nil
+}
+
+// useStrictTransformer removes the leading "use strict" directive of every JS
+// file, startCodeJS adds it once for the whole bundle
+var useStrictTransformer = TransformerFuncs{
+	File: func(_ string, content []byte) ([]byte, error) {
+		return stripLeadingUseStrict(content), nil
+	},
+}
@@ -0,0 +1,77 @@
+package assetmin
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestRegisterTransformer verifies the per file, before and after minify hooks
+// and the keys they can be registered with.
+func TestRegisterTransformer(t *testing.T) {
+	env := setupTestEnv("register_transformer", t)
+	env.AssetsHandler.SetWorkMode(DiskMode)
+	defer env.CleanDirectory()
+	am := env.AssetsHandler
+
+	var files []string
+	require.NoError(t, am.RegisterTransformer(".js", TransformerFuncs{
+		File: func(filePath string, content []byte) ([]byte, error) {
+			files = append(files, filepath.Base(filePath))
+			return bytes.ReplaceAll(content, []byte("__VERSION__"), []byte(`"1.2.3"`)), nil
+		},
+	}))
+	require.NoError(t, am.RegisterTransformer("script.js", TransformerFuncs{
+		After: func(asset string, minified []byte) ([]byte, error) {
+			return append([]byte("/*! "+asset+" */"), minified...), nil
+		},
+	}))
+	require.NoError(t, am.RegisterTransformer("text/css", TransformerFuncs{
+		Before: func(_ string, bundle []byte) ([]byte, error) {
+			return bytes.ReplaceAll(bundle, []byte("$brand"), []byte("red")), nil
+		},
+	}))
+
+	write := func(name, content string) string {
+		path := filepath.Join(env.ModulesDir, "tr", name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+		return path
+	}
+	_ = write("app.js", "'use strict';\nconsole.log(__VERSION__);")
+	_ = write("app.css", ".app { color: $brand; }")
+	_, err := am.ScanDirectories(env.ModulesDir)
+	require.NoError(t, err)
+
+	js, err := os.ReadFile(env.MainJsPath)
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(string(js), "/*! script.js */"), string(js))
+	require.Contains(t, string(js), `console.log("1.2.3")`)
+	// the built-in transformer still strips the directive of each file
+	require.Equal(t, 1, strings.Count(string(js), "use strict"))
+	require.Contains(t, files, "app.js")
+
+	css, err := os.ReadFile(env.MainCssPath)
+	require.NoError(t, err)
+	require.Equal(t, ".app{color:red}", string(css))
+
+	t.Run("error_stops_the_build", func(t *testing.T) {
+		require.NoError(t, am.RegisterTransformer("style.css", TransformerFuncs{
+			File: func(string, []byte) ([]byte, error) { return nil, errors.New("prefixer failed") },
+		}))
+		path := write("other.css", ".other { color: blue; }")
+		err := am.NewFileEventKind("other.css", ".css", path, EventCreate)
+		require.ErrorContains(t, err, "prefixer failed")
+		require.ErrorContains(t, err, "app.css")
+	})
+
+	t.Run("invalid_registrations", func(t *testing.T) {
+		require.Error(t, am.RegisterTransformer(".txt", TransformerFuncs{}))
+		require.Error(t, am.RegisterTransformer("text/css", nil))
+	})
+}
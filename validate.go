@@ -0,0 +1,89 @@
+package assetmin
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"regexp"
+
+	"github.com/tdewolff/parse/v2"
+	"github.com/tdewolff/parse/v2/css"
+	"github.com/tdewolff/parse/v2/js"
+)
+
+// jsMediaType matches the media types handled by the JS minifier
+var jsMediaType = regexp.MustCompile("^(application|text)/(x-)?(java|ecma)script$")
+
+// validateSource parses a JS or CSS source file on its own. A file that fails
+// is quarantined: it stays in its place in the asset but is left out of the
+// bundle until an event brings valid content. Other media types aren't checked.
+func (h *asset) validateSource(filePath string, content []byte) *Diagnostic {
+	var err error
+	switch {
+	case jsMediaType.MatchString(h.mediatype):
+		_, err = js.Parse(parse.NewInputBytes(content), js.Options{})
+	case h.mediatype == "text/css":
+		err = validateCSS(content)
+	default:
+		return nil
+	}
+	if err == nil {
+		return nil
+	}
+
+	d := &Diagnostic{Asset: h.fileOutputName, File: filePath, Message: err.Error(), Quarantined: true}
+	var perr *parse.Error
+	if errors.As(err, &perr) {
+		d.Line, d.Column, d.Message = perr.Line, perr.Column, perr.Message
+		d.Excerpt = excerpt(content, d.Line, d.Column)
+	}
+	return d
+}
+
+// validateCSS reports the grammar errors the minifier would skip and the blocks
+// left open, the parser closes them at the end of the file so in the bundle they
+// would swallow the rules of the next files
+func validateCSS(content []byte) error {
+	p := css.NewParser(parse.NewInputBytes(content), false)
+	for {
+		gt, _, _ := p.Next()
+		if gt == css.ErrorGrammar {
+			if err := p.Err(); err != io.EOF {
+				return err
+			}
+			break
+		}
+	}
+
+	l := css.NewLexer(parse.NewInputBytes(content))
+	var open []int // offsets of the unclosed braces
+	offset := 0
+	for {
+		tt, data := l.Next()
+		if tt == css.ErrorToken {
+			break
+		}
+		switch tt {
+		case css.LeftBraceToken:
+			open = append(open, offset)
+		case css.RightBraceToken:
+			if len(open) > 0 {
+				open = open[:len(open)-1]
+			}
+		}
+		offset += len(data)
+	}
+	if len(open) > 0 {
+		return parse.NewError(bytes.NewReader(content), open[len(open)-1], "unclosed block")
+	}
+	return nil
+}
+
+// invalidFile returns the diagnostic of a quarantined source file, nil when the
+// file is in the bundle or not loaded
+func (h *asset) invalidFile(filePath string) *Diagnostic {
+	if idx := findFileIndex(h.contentMiddle, filePath); idx != -1 {
+		return h.contentMiddle[idx].invalid
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package assetmin
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestQuarantine verifies that a broken file is left out of the bundle, the
+// others keep being served, and that it returns in its place once fixed.
+func TestQuarantine(t *testing.T) {
+	env := setupTestEnv("quarantine", t)
+	defer env.CleanDirectory()
+	am := env.AssetsHandler
+
+	var logs []string
+	am.Logger = func(message ...any) {
+		logs = append(logs, fmt.Sprintln(message...))
+	}
+
+	write := func(name, content string) string {
+		path := filepath.Join(env.ModulesDir, "q", name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+		return path
+	}
+	content := func(fh *asset) string {
+		out, err := fh.GetMinifiedContent(am.minifier())
+		require.NoError(t, err)
+		return string(out)
+	}
+
+	first := write("a.js", "console.log('first');")
+	middle := write("b.js", "console.log('middle');")
+	last := write("c.js", "console.log('last');")
+	for _, path := range []string{first, middle, last} {
+		require.NoError(t, am.NewFileEventKind(filepath.Base(path), ".js", path, EventCreate))
+	}
+
+	write("b.js", "function (")
+	err := am.NewFileEventKind("b.js", ".js", middle, EventWrite)
+	var diag *Diagnostic
+	require.True(t, errors.As(err, &diag), "the event reports why the file was left out")
+	require.True(t, diag.Quarantined)
+	require.Equal(t, middle, diag.File)
+	require.Equal(t, 1, diag.Line)
+
+	js := content(am.mainJsHandler)
+	require.Contains(t, js, "first")
+	require.Contains(t, js, "last")
+	require.NotContains(t, js, "middle")
+	require.Equal(t, []Diagnostic{*diag}, am.Diagnostics())
+	require.Contains(t, strings.Join(logs, ""), "warn: file quarantined asset=script.js path="+middle)
+
+	write("b.js", "console.log('fixed');")
+	require.NoError(t, am.NewFileEventKind("b.js", ".js", middle, EventWrite))
+	js = content(am.mainJsHandler)
+	require.Less(t, strings.Index(js, "first"), strings.Index(js, "fixed"))
+	require.Less(t, strings.Index(js, "fixed"), strings.Index(js, "last"))
+	require.Empty(t, am.Diagnostics())
+	require.Contains(t, strings.Join(logs, ""), "file restored asset=script.js path="+middle)
+
+	t.Run("css", func(t *testing.T) {
+		theme := write("theme.css", ".theme{color:red}")
+		require.NoError(t, am.NewFileEventKind("theme.css", ".css", theme, EventCreate))
+
+		// an unclosed block would swallow the rules of the next files
+		open := write("open.css", ".open{color:blue")
+		require.Error(t, am.NewFileEventKind("open.css", ".css", open, EventCreate))
+		bad := write("bad.css", ".bad{color blue}")
+		require.Error(t, am.NewFileEventKind("bad.css", ".css", bad, EventCreate))
+
+		css := content(am.mainStyleCssHandler)
+		require.Contains(t, css, ".theme{color:red}")
+		require.NotContains(t, css, "open")
+		require.NotContains(t, css, "bad")
+		require.Len(t, am.Diagnostics(), 2)
+	})
+
+	t.Run("scan", func(t *testing.T) {
+		_, err := am.ScanDirectories(env.ModulesDir)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "unclosed block")
+		var diag *Diagnostic
+		require.True(t, errors.As(err, &diag), "the scan keeps the diagnostics of quarantined files")
+		require.True(t, diag.Quarantined)
+	})
+}
@@ -0,0 +1,226 @@
+package assetmin
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+const defaultWatchInterval = 500 * time.Millisecond
+
+// watcher polls the source directories and translates the differences between
+// two snapshots into file events that are sent through NewFileEvent.
+// Polling is used instead of OS notifications so it works on every platform.
+type watcher struct {
+	am       *AssetMin
+	roots    []string
+	interval time.Duration
+	files    map[string]os.FileInfo // last snapshot eg: modules/app/app.js -> info
+	ignores  map[string]os.FileInfo // ignore file of each root, nil when missing
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+// StartWatcher starts monitoring Config.SourceDirs recursively. Files present when
+// it starts are taken as the baseline (use ScanDirectories to load them); from then
+// on created, modified, removed and renamed files, including those inside newly
+// added directories, are fed into the same pipeline as NewFileEvent. A rename is
+// a removed and a created file within one poll that are the same file, or that
+// have the same size, modification time and content, see sameFile.
+func (c *AssetMin) StartWatcher() error {
+	const e = "StartWatcher "
+	if len(c.SourceDirs) == 0 {
+		return errors.New(e + "no SourceDirs configured")
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.watcher != nil {
+		return errors.New(e + "watcher already running")
+	}
+
+	interval := c.WatchInterval
+	if interval <= 0 {
+		interval = defaultWatchInterval
+	}
+
+	w := &watcher{
+		am:       c,
+		roots:    c.SourceDirs,
+		interval: interval,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	w.files = w.snapshot()
+	w.ignores = w.ignoreFiles()
+	c.watcher = w
+
+	go w.run()
+	return nil
+}
+
+// StopWatcher stops the watcher started by StartWatcher and waits until the
+// current poll cycle finishes. It does nothing if no watcher is running.
+func (c *AssetMin) StopWatcher() {
+	c.mu.Lock()
+	w := c.watcher
+	c.watcher = nil
+	c.mu.Unlock()
+
+	if w == nil {
+		return
+	}
+	close(w.stop)
+	<-w.done
+}
+
+func (w *watcher) run() {
+	defer close(w.done)
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-ticker.C:
+			w.poll()
+		}
+	}
+}
+
+// snapshot collects the files currently present under the watched roots
+func (w *watcher) snapshot() map[string]os.FileInfo {
+	files := map[string]os.FileInfo{}
+	for _, root := range w.roots {
+		err := w.am.walkSources(root, func(filePath, extension string) error {
+			info, err := os.Stat(filePath)
+			if err != nil {
+				// the file vanished between the walk and the stat, the next poll reports it
+				return nil
+			}
+			files[filePath] = info
+			return nil
+		})
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			w.am.log().Error("watcher scan", LogKeyPath, root, LogKeyError, err)
+		}
+	}
+	return files
+}
+
+// ignoreFiles returns the state of the IgnoreFileName of every root, nil when missing
+func (w *watcher) ignoreFiles() map[string]os.FileInfo {
+	ignores := make(map[string]os.FileInfo, len(w.roots))
+	for _, root := range w.roots {
+		info, err := os.Stat(filepath.Join(root, IgnoreFileName))
+		if err != nil {
+			info = nil
+		}
+		ignores[root] = info
+	}
+	return ignores
+}
+
+// changed reports whether a file was created, removed or modified between two states
+func changed(old, current os.FileInfo) bool {
+	if old == nil || current == nil {
+		return old != current
+	}
+	return !current.ModTime().Equal(old.ModTime()) || current.Size() != old.Size()
+}
+
+// poll compares the current state of the roots with the previous snapshot and
+// dispatches the resulting events. The ignore files are reloaded first so the
+// snapshot already follows their rules.
+func (w *watcher) poll() {
+	ignores := w.ignoreFiles()
+	for _, root := range w.roots {
+		if changed(w.ignores[root], ignores[root]) {
+			w.am.reloadIgnoreFile(root)
+		}
+	}
+	w.ignores = ignores
+
+	current := w.snapshot()
+
+	var created, modified, removed []string
+	for filePath, info := range current {
+		old, ok := w.files[filePath]
+		if !ok {
+			created = append(created, filePath)
+		} else if changed(old, info) {
+			modified = append(modified, filePath)
+		}
+	}
+	for filePath := range w.files {
+		if _, ok := current[filePath]; !ok {
+			removed = append(removed, filePath)
+		}
+	}
+	sort.Strings(created)
+	sort.Strings(modified)
+	sort.Strings(removed)
+
+	// A file that disappeared and the same file showing up under another path
+	// within one cycle is a rename
+	renamed := map[string]string{} // new path -> old path
+	renamedFrom := map[string]bool{}
+	for _, oldPath := range removed {
+		for _, newPath := range created {
+			if _, taken := renamed[newPath]; !taken && w.sameFile(oldPath, newPath, w.files[oldPath], current[newPath]) {
+				renamed[newPath] = oldPath
+				renamedFrom[oldPath] = true
+				break
+			}
+		}
+	}
+
+	for _, filePath := range removed {
+		if !renamedFrom[filePath] {
+			w.dispatch(filePath, EventRemove)
+		}
+	}
+	for _, filePath := range created {
+		if oldPath, ok := renamed[filePath]; ok {
+			if err := w.am.RenameFile(oldPath, filePath); err != nil {
+				w.am.log().Error("watcher rename", LogKeyEvent, EventRename.String(), LogKeyPath, filePath, LogKeyError, err)
+			}
+			continue
+		}
+		w.dispatch(filePath, EventCreate)
+	}
+	for _, filePath := range modified {
+		w.dispatch(filePath, EventWrite)
+	}
+
+	w.files = current
+}
+
+// sameFile reports whether the file removed from oldPath is the one created at
+// newPath. os.SameFile compares the file identity (the inode on Unix), but on
+// Windows it needs the file at oldPath to still exist, so when it fails the
+// size, the modification time and the content loaded from oldPath are compared.
+func (w *watcher) sameFile(oldPath, newPath string, old, current os.FileInfo) bool {
+	if os.SameFile(old, current) {
+		return true
+	}
+	if old.Size() != current.Size() || !old.ModTime().Equal(current.ModTime()) {
+		return false
+	}
+	loaded := w.am.loadedFileHash(oldPath)
+	if loaded == "" {
+		return false
+	}
+	content, err := os.ReadFile(newPath)
+	return err == nil && contentHash(content) == loaded
+}
+
+func (w *watcher) dispatch(filePath string, kind EventKind) {
+	if err := w.am.NewFileEventKind(filepath.Base(filePath), filepath.Ext(filePath), filePath, kind); err != nil {
+		w.am.log().Error("watcher event", LogKeyEvent, kind.String(), LogKeyPath, filePath, LogKeyError, err)
+	}
+}
@@ -0,0 +1,141 @@
+package assetmin
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestWatcher verifies that the polling watcher detects created, modified,
+// renamed and removed files (also inside new directories) and ignores the
+// output files even when the output directory lives inside a source root.
+func TestWatcher(t *testing.T) {
+	env := setupTestEnv("watcher", t)
+	defer env.CleanDirectory()
+	env.CreateModulesDir()
+
+	am := env.AssetsHandler
+	am.SourceDirs = []string{env.BaseDir}
+	am.SetWorkMode(DiskMode)
+
+	readJs := func() string {
+		content, err := os.ReadFile(env.MainJsPath)
+		require.NoError(t, err)
+		return string(content)
+	}
+
+	w := &watcher{am: am, roots: am.SourceDirs}
+	w.files = w.snapshot()
+
+	// create inside a directory added after the watcher started
+	appPath := filepath.Join(env.ModulesDir, "module1", "app.js")
+	require.NoError(t, os.MkdirAll(filepath.Dir(appPath), 0755))
+	require.NoError(t, os.WriteFile(appPath, []byte("console.log('Module One');"), 0644))
+	w.poll()
+	require.Contains(t, readJs(), "Module One")
+
+	// the output written by the previous poll must not become an event
+	require.NotContains(t, w.files, env.MainJsPath)
+
+	// write
+	require.NoError(t, os.WriteFile(appPath, []byte("console.log('Module One Updated');"), 0644))
+	w.poll()
+	require.Contains(t, readJs(), "Module One Updated")
+
+	// rename
+	renamedPath := filepath.Join(env.ModulesDir, "module1", "main.js")
+	require.NoError(t, os.Rename(appPath, renamedPath))
+	w.poll()
+	require.Contains(t, readJs(), "Module One Updated")
+	require.Contains(t, w.files, renamedPath)
+	require.NotContains(t, w.files, appPath)
+
+	// remove
+	require.NoError(t, os.Remove(renamedPath))
+	w.poll()
+	require.NotContains(t, readJs(), "Module One")
+
+	t.Run("rename_without_file_identity", func(t *testing.T) {
+		// on Windows os.SameFile can't identify a moved file, a copy with the
+		// same size, time and content stands in for it
+		paths := func() (out []string) {
+			for _, f := range am.mainJsHandler.contentMiddle {
+				out = append(out, filepath.Base(f.path))
+			}
+			return out
+		}
+		dir := filepath.Join(env.ModulesDir, "moved")
+		require.NoError(t, os.MkdirAll(dir, 0755))
+		for _, name := range []string{"a.js", "b.js", "c.js"} {
+			require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte("console.log('"+name+"');"), 0644))
+		}
+		w.poll()
+		require.Equal(t, []string{"a.js", "b.js", "c.js"}, paths())
+
+		oldPath, newPath := filepath.Join(dir, "b.js"), filepath.Join(dir, "z.js")
+		info, err := os.Stat(oldPath)
+		require.NoError(t, err)
+		content, err := os.ReadFile(oldPath)
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(newPath, content, 0644))
+		require.NoError(t, os.Chtimes(newPath, info.ModTime(), info.ModTime()))
+		require.NoError(t, os.Remove(oldPath))
+
+		w.poll()
+		require.Equal(t, []string{"a.js", "z.js", "c.js"}, paths(), "the rename keeps the bundle order")
+	})
+
+	t.Run("start_and_stop", func(t *testing.T) {
+		am.WatchInterval = 10 * time.Millisecond
+		require.NoError(t, am.StartWatcher())
+		require.Error(t, am.StartWatcher(), "a second watcher must be rejected")
+
+		cssPath := filepath.Join(env.ModulesDir, "module2", "style.css")
+		require.NoError(t, os.MkdirAll(filepath.Dir(cssPath), 0755))
+		require.NoError(t, os.WriteFile(cssPath, []byte(".watched { color: red; }"), 0644))
+
+		require.Eventually(t, func() bool {
+			content, err := os.ReadFile(env.MainCssPath)
+			return err == nil && string(content) == ".watched{color:red}"
+		}, 2*time.Second, 10*time.Millisecond)
+
+		am.StopWatcher()
+		am.StopWatcher() // stopping twice is harmless
+	})
+}
+
+// TestWatcherAbsoluteRoot verifies that the outputs are never read back as
+// sources when the root is absolute and OutputDir is relative inside it.
+func TestWatcherAbsoluteRoot(t *testing.T) {
+	env := setupTestEnv("watcher_absolute_root", t)
+	defer env.CleanDirectory()
+	env.CreateModulesDir()
+
+	root, err := filepath.Abs(env.BaseDir)
+	require.NoError(t, err)
+	am := env.AssetsHandler
+	am.SourceDirs = []string{root}
+	am.SetWorkMode(DiskMode)
+
+	w := &watcher{am: am, roots: am.SourceDirs}
+	w.files = w.snapshot()
+
+	appPath := filepath.Join(root, "modules", "a.js")
+	require.NoError(t, os.WriteFile(appPath, []byte("console.log('changed');"), 0644))
+	w.poll()
+	first, err := os.ReadFile(env.MainJsPath)
+	require.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		w.poll()
+	}
+	require.NotContains(t, w.files, filepath.Join(root, "web", "public", "script.js"))
+	js, err := os.ReadFile(env.MainJsPath)
+	require.NoError(t, err)
+	require.Equal(t, string(first), string(js), "the bundle must not grow with its own output")
+	require.Equal(t, 1, strings.Count(string(js), "changed"))
+}